@@ -0,0 +1,149 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+//Package analyzers runs go/analysis analyzers in process on the packages being linted
+package analyzers
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"runtime/debug"
+
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/linters"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+)
+
+//Run runs the enabled analysis linters of runner on roots, usually the Roots of a loader.Program.
+//It returns one result per analyzer and package. Analyzers that fail or panic get a *lint.ToolError.
+//Packages that do not compile are not analyzed, their errors are reported by the build stage.
+//Once ctx is done the analyzers are not run on the packages left, their results carry the error of ctx.
+func Run(ctx context.Context, runner *lint.Runner, roots []*packages.Package) (results []lint.Result) {
+	list := runner.EnabledLinters(linters.ScopeAnalysis)
+	defer func() {
+		if r := recover(); r != nil {
+			results = nil
+			for _, linter := range list {
+				toolErr := &lint.ToolError{Linter: linter.Name, Reason: "panicked", Output: fmt.Sprintf("%v\n%s", r, debug.Stack())}
+				results = append(results, lint.Result{Task: lint.Task{Linter: linter}, Err: toolErr})
+			}
+		}
+	}()
+	if len(list) == 0 || len(roots) == 0 {
+		return results
+	}
+	if ctx.Err() != nil {
+		for _, linter := range list {
+			results = append(results, lint.Result{Task: lint.Task{Linter: linter}, Err: ctx.Err()})
+		}
+		return results
+	}
+
+	var pkgs []*packages.Package
+	for _, pkg := range roots {
+		if len(pkg.Errors) == 0 {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+
+	byAnalyzer := make(map[*analysis.Analyzer]linters.Linter, len(list))
+	analyzers := make([]*analysis.Analyzer, 0, len(list))
+	for _, linter := range list {
+		analyzer := interruptible(ctx, linter.Analyzer)
+		byAnalyzer[analyzer] = linter
+		analyzers = append(analyzers, analyzer)
+	}
+	graph, err := checker.Analyze(analyzers, pkgs, nil)
+	if err != nil {
+		for _, linter := range list {
+			toolErr := &lint.ToolError{Linter: linter.Name, Reason: "could not be run", Err: err}
+			results = append(results, lint.Result{Task: lint.Task{Linter: linter}, Err: toolErr})
+		}
+		return results
+	}
+
+	for _, action := range graph.Roots {
+		linter := byAnalyzer[action.Analyzer]
+		result := lint.Result{
+			Task:     lint.Task{Linter: linter, Targets: []string{action.Package.PkgPath}},
+			Duration: action.Duration,
+		}
+		switch {
+		case action.Err != nil && ctx.Err() != nil:
+			result.Err = ctx.Err()
+		case action.Err != nil:
+			result.Err = &lint.ToolError{Linter: linter.Name, Reason: "failed", Err: action.Err}
+		}
+		var issues []lint.Issue
+		for _, diagnostic := range action.Diagnostics {
+			issues = append(issues, toIssue(runner.Root, linter.Name, action.Package.Fset, diagnostic))
+		}
+		if result.Err == nil {
+			result.Issues, result.Err = runner.Ignore(linter.Name, issues)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+//interruptible returns a copy of analyzer that fails without running once ctx is done.
+//checker.Analyze takes no context, the copy is what stops it between packages and passes.
+func interruptible(ctx context.Context, analyzer *analysis.Analyzer) *analysis.Analyzer {
+	wrapped := *analyzer
+	wrapped.Run = func(pass *analysis.Pass) (interface{}, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return analyzer.Run(pass)
+	}
+	return &wrapped
+}
+
+//toIssue converts an analyzer diagnostic and its suggested fixes into an issue
+func toIssue(root, name string, fset *token.FileSet, diagnostic analysis.Diagnostic) (issue lint.Issue) {
+	position := fset.Position(diagnostic.Pos)
+	issue = lint.Issue{
+		Linter:   name,
+		Path:     lint.RelPath(root, position.Filename),
+		Line:     position.Line,
+		Col:      position.Column,
+		Message:  diagnostic.Message,
+		Severity: lint.SeverityError,
+		Category: diagnostic.Category,
+	}
+	for _, suggested := range diagnostic.SuggestedFixes {
+		fix := lint.Fix{Message: suggested.Message}
+		for _, textEdit := range suggested.TextEdits {
+			start := fset.Position(textEdit.Pos)
+			end := start
+			if textEdit.End.IsValid() {
+				end = fset.Position(textEdit.End)
+			}
+			fix.Edits = append(fix.Edits, lint.Edit{
+				Path:    lint.RelPath(root, start.Filename),
+				Start:   start.Offset,
+				End:     end.Offset,
+				NewText: string(textEdit.NewText),
+			})
+		}
+		issue.Fixes = append(issue.Fixes, fix)
+	}
+	return issue
+}
@@ -0,0 +1,134 @@
+package analyzers_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/lagarciag/codenanny/analyzers"
+	"github.com/lagarciag/codenanny/config"
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/loader"
+)
+
+func TestMain(t *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	formatter := &log.TextFormatter{}
+	formatter.ForceColors = true
+	formatter.DisableTimestamp = true
+	log.SetFormatter(formatter)
+	v := t.Run()
+	os.Exit(v)
+
+}
+
+//newRunner writes a go module with the passed files and returns a runner for it
+func newRunner(t *testing.T, files map[string]string, conf config.CodeNannyConfig) (runner *lint.Runner) {
+	root := t.TempDir()
+	files["go.mod"] = "module example.com/nanny\n\ngo 1.24\n"
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runner, err := lint.NewRunner(root, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runner
+}
+
+//load loads the packages matching patterns in the runner root
+func load(t *testing.T, runner *lint.Runner, patterns ...string) (program *loader.Program) {
+	program, err := loader.Load(context.Background(), runner.Root, patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func TestRun(t *testing.T) {
+	runner := newRunner(t, map[string]string{
+		"printer/printer.go": "package printer\n\nimport \"fmt\"\n\nfunc Print(msg string) {\n\tfmt.Printf(msg)\n}\n",
+		"printer/printer_test.go": "package printer\n\nimport (\n\t\"fmt\"\n\t\"testing\"\n)\n\n" +
+			"func TestPrint(t *testing.T) {\n\tfmt.Printf(\"%d\\n\", \"one\")\n}\n",
+		"broken/broken.go": "package broken\n\nfunc Broken() int {\n\treturn \"one\"\n}\n",
+	}, config.CodeNannyConfig{})
+
+	results := analyzers.Run(context.Background(), runner, load(t, runner, "./printer", "./broken").Roots())
+	issues := make(map[string][]lint.Issue)
+	for _, result := range results {
+		if result.Err != nil {
+			t.Error(result.Task.Linter.Name, "failed:", result.Err)
+		}
+		issues[result.Task.Linter.Name] = append(issues[result.Task.Linter.Name], result.Issues...)
+	}
+
+	printf := issues["printf"]
+	if len(printf) != 2 {
+		t.Fatal("printf must report the package and its tests:", printf)
+	}
+	lint.SortIssues(printf)
+	if printf[0].Path != filepath.Join("printer", "printer.go") || printf[0].Line != 6 {
+		t.Error("Unexpected printf issue:", printf[0])
+	}
+	if len(printf[0].Fixes) == 0 || len(printf[0].Fixes[0].Edits) == 0 {
+		t.Error("The suggested fixes of the analyzer must be kept:", printf[0])
+	}
+	if printf[1].Path != filepath.Join("printer", "printer_test.go") {
+		t.Error("Test files must be analyzed:", printf[1])
+	}
+
+	for name, list := range issues {
+		for _, issue := range list {
+			if issue.Path == filepath.Join("broken", "broken.go") {
+				t.Error("Packages that do not compile must not be analyzed:", name, issue)
+			}
+		}
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	runner := newRunner(t, map[string]string{
+		"printer.go": "package printer\n\nimport \"fmt\"\n\nfunc Print(msg string) {\n\tfmt.Printf(msg)\n}\n",
+	}, config.CodeNannyConfig{
+		Disabled:      map[string]bool{"shadow": true},
+		IgnorePattern: map[string][]config.IgnorePattern{"printf": {{Pattern: "non-constant format"}}},
+	})
+
+	for _, result := range analyzers.Run(context.Background(), runner, load(t, runner, ".").Roots()) {
+		if result.Task.Linter.Name == "shadow" {
+			t.Error("Disabled analyzers must not run")
+		}
+		if len(result.Issues) > 0 {
+			t.Error("Unexpected issues:", result.Issues)
+		}
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	runner := newRunner(t, map[string]string{
+		"printer.go": "package printer\n\nimport \"fmt\"\n\nfunc Print(msg string) {\n\tfmt.Printf(msg)\n}\n",
+	}, config.CodeNannyConfig{})
+	program := load(t, runner, ".")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := analyzers.Run(ctx, runner, program.Roots())
+	if len(results) == 0 {
+		t.Fatal("A canceled run must report the analyzers it did not run")
+	}
+	for _, result := range results {
+		if !errors.Is(result.Err, context.Canceled) || len(result.Issues) > 0 {
+			t.Error("A canceled run must not analyze anything:", result.Task.Linter.Name, result.Err, result.Issues)
+		}
+	}
+}
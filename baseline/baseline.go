@@ -0,0 +1,243 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+//Package baseline keeps the issues known when codenanny was adopted in a file committed with the code,
+//so only the new issues fail the run
+package baseline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lagarciag/codenanny/lint"
+)
+
+//DefaultFile is the baseline file looked for in the root of the repo
+const DefaultFile = ".codenanny-baseline.json"
+
+//Version is the format of the baseline files written
+const Version = 1
+
+var (
+	//position matches the positions quoted in messages, they move with unrelated changes
+	position = regexp.MustCompile(`(\.go):\d+(?::\d+)?`)
+	//spaces matches the runs of white space
+	spaces = regexp.MustCompile(`\s+`)
+)
+
+//Entry is an issue of the baseline. Path, Linter and Message are there for the readers of the file,
+//only the fingerprint is matched.
+type Entry struct {
+	Fingerprint string `json:"fingerprint"`
+	Path        string `json:"path"`
+	Linter      string `json:"linter"`
+	Message     string `json:"message"`
+	//Count is the number of issues with the fingerprint, identical lines of a file give identical issues
+	Count int `json:"count"`
+}
+
+//Baseline is the content of a baseline file
+type Baseline struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+//Fingerprint identifies issue whatever the line it is at: it hashes the file, the category, the normalized
+//message without the positions it quotes and the content of the line, white space aside. The linter is left out,
+//it is the one of the linters reporting the issue that wins, see lint.Dedupe.
+//source is the content of the line of the issue, empty when it has none.
+func Fingerprint(issue lint.Issue, source string) string {
+	message := lint.NormalizeMessage(position.ReplaceAllString(issue.Message, "$1"))
+	source = strings.TrimSpace(spaces.ReplaceAllString(source, " "))
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		filepath.ToSlash(filepath.Clean(issue.Path)), issue.Category, message, source,
+	}, "\x00")))
+	return hex.EncodeToString(hash[:8])
+}
+
+//sources reads the lines of the files of the issues once
+type sources struct {
+	root  string
+	files map[string][]string
+}
+
+//line returns the content of the line of issue, empty when it has none or the file can't be read
+func (s *sources) line(issue lint.Issue) string {
+	if issue.Path == "" || issue.Line <= 0 {
+		return ""
+	}
+	path := issue.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.root, path)
+	}
+	lines, found := s.files[path]
+	if !found {
+		content, err := ioutil.ReadFile(path)
+		if err == nil {
+			lines = strings.Split(string(content), "\n")
+		}
+		s.files[path] = lines
+	}
+	if issue.Line > len(lines) {
+		return ""
+	}
+	return lines[issue.Line-1]
+}
+
+//fingerprints returns the fingerprint of each issue, the files being relative to root
+func fingerprints(root string, issues []lint.Issue) (prints []string) {
+	src := &sources{root: root, files: make(map[string][]string)}
+	for _, issue := range issues {
+		prints = append(prints, Fingerprint(issue, src.line(issue)))
+	}
+	return prints
+}
+
+//New returns the baseline of issues, the files being relative to root
+func New(root string, issues []lint.Issue) (baseline *Baseline) {
+	baseline = &Baseline{Version: Version}
+	byPrint := make(map[string]int)
+	for id, key := range fingerprints(root, issues) {
+		if entry, found := byPrint[key]; found {
+			baseline.Entries[entry].Count++
+			continue
+		}
+		byPrint[key] = len(baseline.Entries)
+		baseline.Entries = append(baseline.Entries, Entry{
+			Fingerprint: key,
+			Path:        filepath.ToSlash(issues[id].Path),
+			Linter:      issues[id].Linter,
+			Message:     issues[id].Message,
+			Count:       1,
+		})
+	}
+	baseline.sort()
+	return baseline
+}
+
+//Load reads the baseline file at path
+func Load(path string) (baseline *Baseline, err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	baseline = &Baseline{}
+	if err = json.Unmarshal(content, baseline); err != nil {
+		return nil, fmt.Errorf("invalid baseline %s:%s", path, err.Error())
+	}
+	if baseline.Version > Version {
+		return nil, fmt.Errorf("baseline %s has version %d, this codenanny reads up to %d", path, baseline.Version, Version)
+	}
+	return baseline, nil
+}
+
+//Save writes the baseline to path, sorted so it diffs well
+func (b *Baseline) Save(path string) (err error) {
+	b.sort()
+	content, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(content, '\n'), 0644)
+}
+
+//Filter returns the issues the baseline does not list and the number of issues it lists, the files being relative to root
+func (b *Baseline) Filter(root string, issues []lint.Issue) (fresh []lint.Issue, known int) {
+	left := b.counts()
+	for id, key := range fingerprints(root, issues) {
+		if left[key] > 0 {
+			left[key]--
+			known++
+			continue
+		}
+		fresh = append(fresh, issues[id])
+	}
+	return fresh, known
+}
+
+//Prune drops the entries of the files below dir that issues no longer have and returns how many issues were dropped.
+//dir is relative to root, entries of other files are kept as they were not linted.
+func (b *Baseline) Prune(root, dir string, issues []lint.Issue) (pruned int) {
+	found := make(map[string]int)
+	for _, key := range fingerprints(root, issues) {
+		found[key]++
+	}
+	dir = filepath.ToSlash(filepath.Clean(dir))
+	var kept []Entry
+	for _, entry := range b.Entries {
+		if dir != "." && entry.Path != dir && !strings.HasPrefix(entry.Path, dir+"/") {
+			kept = append(kept, entry)
+			continue
+		}
+		count := entry.Count
+		if found[entry.Fingerprint] < count {
+			count = found[entry.Fingerprint]
+		}
+		pruned += entry.Count - count
+		if count > 0 {
+			entry.Count = count
+			kept = append(kept, entry)
+		}
+	}
+	b.Entries = kept
+	return pruned
+}
+
+//Len returns the number of issues of the baseline
+func (b *Baseline) Len() (count int) {
+	for _, entry := range b.Entries {
+		count += entry.Count
+	}
+	return count
+}
+
+//counts returns the number of issues of each fingerprint
+func (b *Baseline) counts() (counts map[string]int) {
+	counts = make(map[string]int, len(b.Entries))
+	for _, entry := range b.Entries {
+		count := entry.Count
+		if count <= 0 {
+			count = 1
+		}
+		counts[entry.Fingerprint] += count
+	}
+	return counts
+}
+
+//sort orders the entries by file, linter, message and fingerprint
+func (b *Baseline) sort() {
+	sort.Slice(b.Entries, func(i, j int) bool {
+		x, y := b.Entries[i], b.Entries[j]
+		if x.Path != y.Path {
+			return x.Path < y.Path
+		}
+		if x.Linter != y.Linter {
+			return x.Linter < y.Linter
+		}
+		if x.Message != y.Message {
+			return x.Message < y.Message
+		}
+		return x.Fingerprint < y.Fingerprint
+	})
+}
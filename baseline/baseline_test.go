@@ -0,0 +1,118 @@
+package baseline_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/lagarciag/codenanny/baseline"
+	"github.com/lagarciag/codenanny/lint"
+)
+
+func TestMain(t *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	formatter := &log.TextFormatter{}
+	formatter.ForceColors = true
+	formatter.DisableTimestamp = true
+	log.SetFormatter(formatter)
+	v := t.Run()
+	os.Exit(v)
+
+}
+
+//write writes the files of a map in root
+func write(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	issue := lint.Issue{Linter: "vet", Path: "a.go", Line: 12, Message: "x redeclared, other declaration at a.go:7:2"}
+	moved := issue
+	moved.Line, moved.Message = 40, "x redeclared, other declaration at a.go:30:2"
+	if baseline.Fingerprint(issue, "\tx := 1") != baseline.Fingerprint(moved, "  x :=  1 ") {
+		t.Error("Fingerprints must not change when the code moves")
+	}
+	if baseline.Fingerprint(issue, "\tx := 1") == baseline.Fingerprint(issue, "\tx := 2") {
+		t.Error("Fingerprints must change with the content of the line")
+	}
+	//Another linter may win the dedupe of the issue, or word it another way
+	other := issue
+	other.Linter, other.Message = "staticcheck", "X redeclared: other declaration at a.go:9:2"
+	if baseline.Fingerprint(issue, "\tx := 1") != baseline.Fingerprint(other, "\tx := 1") {
+		t.Error("Fingerprints must not change with the linter")
+	}
+	other.Category = "SA4006"
+	if baseline.Fingerprint(issue, "\tx := 1") == baseline.Fingerprint(other, "\tx := 1") {
+		t.Error("Fingerprints must change with the category")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, map[string]string{
+		"a.go": "package a\n\nfunc A() {\n\tf()\n\tf()\n}\n",
+	})
+	issue := func(line int, message string) lint.Issue {
+		return lint.Issue{Linter: "errcheck", Path: "a.go", Line: line, Message: message}
+	}
+	known := baseline.New(root, []lint.Issue{issue(4, "unchecked"), issue(5, "unchecked")})
+	if len(known.Entries) != 1 || known.Entries[0].Count != 2 || known.Len() != 2 {
+		t.Fatal("Identical issues must share an entry:", known.Entries)
+	}
+
+	//Two lines were added above, and a third call was added
+	write(t, root, map[string]string{
+		"a.go": "package a\n\n//A calls f\n//three times\nfunc A() {\n\tf()\n\tf()\n\tf()\n}\n",
+	})
+	fresh, count := known.Filter(root, []lint.Issue{issue(6, "unchecked"), issue(7, "unchecked"), issue(8, "unchecked"), issue(5, "too long")})
+	if count != 2 || len(fresh) != 2 || fresh[0].Line != 8 || fresh[1].Message != "too long" {
+		t.Error("Only the new issues must be left:", count, fresh)
+	}
+}
+
+func TestSaveLoadPrune(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, map[string]string{
+		"a/a.go": "package a\n",
+		"b/b.go": "package b\n",
+	})
+	issues := []lint.Issue{
+		{Linter: "golint", Path: filepath.Join("a", "a.go"), Line: 1, Message: "package comment"},
+		{Linter: "golint", Path: filepath.Join("b", "b.go"), Line: 1, Message: "package comment"},
+		{Linter: "lll", Path: filepath.Join("b", "b.go"), Line: 1, Message: "line too long"},
+	}
+	path := filepath.Join(root, baseline.DefaultFile)
+	if err := baseline.New(root, issues).Save(path); err != nil {
+		t.Fatal(err)
+	}
+	known, err := baseline.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if known.Version != baseline.Version || len(known.Entries) != 3 || known.Entries[0].Path != "a/a.go" {
+		t.Fatal("Unexpected baseline:", known)
+	}
+
+	//Only b was linted, and its golint issue was fixed
+	if pruned := known.Prune(root, "b", issues[2:]); pruned != 1 {
+		t.Error("The fixed issue must be pruned, got", pruned)
+	}
+	if len(known.Entries) != 2 || known.Entries[0].Path != "a/a.go" || known.Entries[1].Linter != "lll" {
+		t.Error("The issues of the dirs not linted must be kept:", known.Entries)
+	}
+
+	write(t, root, map[string]string{baseline.DefaultFile: `{"version": 99, "entries": []}`})
+	if _, err = baseline.Load(path); err == nil {
+		t.Error("Baselines of newer versions must be rejected")
+	}
+}
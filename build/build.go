@@ -0,0 +1,259 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+//Package build is the build stage: it reports the compile errors of the packages being linted, test files
+//included, and keeps the linters that need type information off the code that does not compile
+package build
+
+import (
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/linters"
+	"github.com/lagarciag/codenanny/loader"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/tools/go/packages"
+)
+
+//Name names the issues of the build stage
+const Name = "build"
+
+//errorPosition parses the position of a packages.Error: path:line[:col]
+var errorPosition = regexp.MustCompile(`^(.+?):(\d+)(?::(\d+))?$`)
+
+//Broken is the code of a program that does not compile, or imports a package that does not
+type Broken struct {
+	//Root is the directory the program was loaded from
+	Root string
+	//Packages are the import paths of the broken packages
+	Packages map[string]bool
+	//Dirs are the directories of the broken packages relative to Root
+	Dirs map[string]bool
+	//ids are the IDs of the broken packages, test variants and test mains included
+	ids map[string]bool
+}
+
+//Check reports the compile errors of the packages of program and returns what does not compile.
+//The packages were type checked when loaded, nothing is built again. There is no result when everything compiles.
+func Check(program *loader.Program) (results []lint.Result, broken Broken) {
+	start := time.Now()
+	broken = Broken{Root: program.Root, Packages: make(map[string]bool), Dirs: make(map[string]bool), ids: make(map[string]bool)}
+	if len(program.Packages) == 0 {
+		return results, broken
+	}
+
+	//A package is broken when it, or any package it imports, has errors
+	ill := make(map[*packages.Package]bool)
+	var visit func(pkg *packages.Package) bool
+	visit = func(pkg *packages.Package) bool {
+		if bad, found := ill[pkg]; found {
+			return bad
+		}
+		ill[pkg] = false
+		bad := len(pkg.Errors) > 0
+		for _, imported := range pkg.Imports {
+			if visit(imported) {
+				bad = true
+			}
+		}
+		ill[pkg] = bad
+		return bad
+	}
+
+	linted := make(map[string]bool, len(program.Packages))
+	for _, pkg := range program.Packages {
+		linted[pkg.PkgPath] = true
+	}
+	var issues []lint.Issue
+	seen := make(map[string]bool)
+	add := func(issue lint.Issue) {
+		if key := issue.String(); !seen[key] {
+			seen[key] = true
+			issues = append(issues, issue)
+		}
+	}
+	for _, pkg := range program.Packages {
+		if !visit(pkg) {
+			continue
+		}
+		broken.ids[pkg.ID] = true
+		if strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+		dir := pkgDir(program.Root, pkg)
+		broken.Packages[strings.TrimSuffix(pkg.PkgPath, "_test")] = true
+		broken.Dirs[dir] = true
+		//The errors of a package are found again in its test variant
+		for _, pkgErr := range pkg.Errors {
+			add(errorIssue(program.Root, dir, pkgErr))
+		}
+		//The errors of the broken packages that are not linted are reported where they are imported
+		for importPath, imported := range pkg.Imports {
+			if !ill[imported] || linted[imported.PkgPath] {
+				continue
+			}
+			issue := lint.Issue{Linter: Name, Path: dir, Severity: lint.SeverityError}
+			issue.Message = fmt.Sprintf("imports %s, which does not compile", imported.PkgPath)
+			if pkgErr := firstError(imported, ill, make(map[*packages.Package]bool)); pkgErr != nil {
+				cause := errorIssue(program.Root, "", *pkgErr)
+				issue.Message = fmt.Sprintf("%s: %s", issue.Message, cause)
+			}
+			if position, found := importSite(pkg, importPath); found {
+				issue.Path = lint.RelPath(program.Root, position.Filename)
+				issue.Line, issue.Col = position.Line, position.Column
+			}
+			log.Warnf("%s imports %s, which does not compile", pkg.PkgPath, imported.PkgPath)
+			add(issue)
+		}
+	}
+	if len(issues) == 0 {
+		return results, broken
+	}
+	results = append(results, lint.Result{
+		Task:     lint.Task{Linter: linters.Linter{Name: Name}, Targets: program.PkgPaths()},
+		Issues:   issues,
+		Duration: time.Since(start),
+	})
+	return results, broken
+}
+
+//errorIssue returns the issue of a package error, at dir when the error has no position
+func errorIssue(root, dir string, pkgErr packages.Error) (issue lint.Issue) {
+	issue = lint.Issue{Linter: Name, Path: dir, Message: pkgErr.Msg, Severity: lint.SeverityError}
+	if match := errorPosition.FindStringSubmatch(pkgErr.Pos); match != nil {
+		issue.Path = lint.RelPath(root, match[1])
+		issue.Line, _ = strconv.Atoi(match[2])
+		issue.Col, _ = strconv.Atoi(match[3])
+	}
+	return issue
+}
+
+//firstError returns the first error of pkg or of the broken packages it imports, nil if none is found
+func firstError(pkg *packages.Package, ill, visited map[*packages.Package]bool) *packages.Error {
+	if visited[pkg] {
+		return nil
+	}
+	visited[pkg] = true
+	if len(pkg.Errors) > 0 {
+		return &pkg.Errors[0]
+	}
+	for _, imported := range pkg.Imports {
+		if ill[imported] {
+			if pkgErr := firstError(imported, ill, visited); pkgErr != nil {
+				return pkgErr
+			}
+		}
+	}
+	return nil
+}
+
+//importSite returns the position of the import of importPath in the files of pkg
+func importSite(pkg *packages.Package, importPath string) (position token.Position, found bool) {
+	for _, file := range pkg.Syntax {
+		for _, spec := range file.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil && path == importPath {
+				return pkg.Fset.Position(spec.Pos()), true
+			}
+		}
+	}
+	return position, false
+}
+
+//IsBroken tells if some code does not compile
+func (b Broken) IsBroken() bool {
+	return len(b.ids) > 0
+}
+
+//Compiled returns the packages of program that compile, with their tests
+func (b Broken) Compiled(program *loader.Program) (compiled *loader.Program) {
+	compiled = &loader.Program{Root: program.Root}
+	for _, pkg := range program.Packages {
+		if !b.ids[pkg.ID] && !b.Packages[strings.TrimSuffix(pkg.PkgPath, "_test")] {
+			compiled.Packages = append(compiled.Packages, pkg)
+		}
+	}
+	return compiled
+}
+
+//Skip removes the broken targets from the tasks of the linters that need type information.
+//Tasks left without targets are not run, they are returned as skipped results.
+func (b Broken) Skip(tasks []lint.Task) (kept []lint.Task, skipped []lint.Result) {
+	for _, task := range tasks {
+		if !b.IsBroken() || !task.Linter.NeedsTypes {
+			kept = append(kept, task)
+			continue
+		}
+		var targets []string
+		for _, target := range task.Targets {
+			if !b.targets(task.Linter.Scope, target) {
+				targets = append(targets, target)
+			}
+		}
+		if len(targets) < len(task.Targets) {
+			log.Debugf("Skipping %s on %d targets that do not compile", task.Linter.Name, len(task.Targets)-len(targets))
+		}
+		if len(targets) == 0 {
+			skipped = append(skipped, lint.Result{Task: task, Skipped: true})
+			continue
+		}
+		task.Targets = targets
+		kept = append(kept, task)
+	}
+	return kept, skipped
+}
+
+//targets tells if a target of a linter of scope holds broken code
+func (b Broken) targets(scope linters.Scope, target string) bool {
+	switch scope {
+	case linters.ScopeFile:
+		return b.Dirs[filepath.Dir(b.rel(target))]
+	case linters.ScopeDir:
+		return b.Dirs[b.rel(target)]
+	case linters.ScopeRecursive:
+		root := b.rel(target)
+		for dir := range b.Dirs {
+			if root == "." || dir == root || strings.HasPrefix(dir, root+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+	return b.Packages[target]
+}
+
+//rel returns path cleaned and relative to the root
+func (b Broken) rel(path string) string {
+	return filepath.Clean(lint.RelPath(b.Root, path))
+}
+
+//pkgDir returns the directory of pkg relative to root
+func pkgDir(root string, pkg *packages.Package) string {
+	files := pkg.GoFiles
+	if len(files) == 0 {
+		files = pkg.CompiledGoFiles
+	}
+	if len(files) == 0 {
+		return "."
+	}
+	return lint.RelPath(root, filepath.Dir(files[0]))
+}
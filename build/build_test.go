@@ -0,0 +1,156 @@
+package build_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/lagarciag/codenanny/build"
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/linters"
+	"github.com/lagarciag/codenanny/loader"
+)
+
+func TestMain(t *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	formatter := &log.TextFormatter{}
+	formatter.ForceColors = true
+	formatter.DisableTimestamp = true
+	log.SetFormatter(formatter)
+	v := t.Run()
+	os.Exit(v)
+
+}
+
+//load writes a go module with the passed files and loads every package of it
+func load(t *testing.T, files map[string]string, patterns ...string) (program *loader.Program) {
+	root := t.TempDir()
+	files["go.mod"] = "module example.com/nanny\n\ngo 1.16\n"
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	program, err := loader.Load(context.Background(), root, patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func TestCheck(t *testing.T) {
+	program := load(t, map[string]string{
+		"ok/ok.go":                 "package ok\n",
+		"broken/broken.go":         "package broken\n\nfunc Broken() int {\n\treturn \"one\"\n}\n",
+		"brokentest/b.go":          "package brokentest\n",
+		"brokentest/b_test.go":     "package brokentest\n\nvar x int = \"one\"\n",
+		"importer/importer.go":     "package importer\n\nimport \"example.com/nanny/broken\"\n\nvar X = broken.Broken\n",
+		"importer/importer_doc.go": "package importer\n",
+	})
+
+	results, broken := build.Check(program)
+	if len(results) != 1 || results[0].Task.Linter.Name != build.Name {
+		t.Fatal("Unexpected results:", results)
+	}
+	expected := []string{
+		filepath.Join("broken", "broken.go") + ":4:9: cannot use \"one\" (untyped string constant) as int value in return statement",
+		filepath.Join("brokentest", "b_test.go") + ":3:13: cannot use \"one\" (untyped string constant) as int value in variable declaration",
+	}
+	issues := results[0].Issues
+	lint.SortIssues(issues)
+	if len(issues) != len(expected) {
+		t.Fatal("Unexpected issues:", issues)
+	}
+	for id, issue := range issues {
+		if issue.String() != expected[id] {
+			t.Errorf("Expected %q, got %q", expected[id], issue.String())
+		}
+	}
+
+	if !broken.IsBroken() || broken.Packages["example.com/nanny/ok"] {
+		t.Error("Only the packages that do not compile must be broken:", broken.Packages)
+	}
+	for _, pkg := range []string{"broken", "brokentest", "importer"} {
+		if !broken.Packages["example.com/nanny/"+pkg] || !broken.Dirs[pkg] {
+			t.Error("Package must be broken:", pkg)
+		}
+	}
+	if paths := broken.Compiled(program).PkgPaths(); len(paths) != 1 || paths[0] != "example.com/nanny/ok" {
+		t.Error("Only the packages that compile must be kept:", paths)
+	}
+}
+
+func TestCheckBrokenDependency(t *testing.T) {
+	//Only a is linted, the package it imports does not compile
+	program := load(t, map[string]string{
+		"a/a.go": "package a\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/nanny/b\"\n)\n\nfunc A() {\n\tfmt.Printf(\"%d\", b.B)\n}\n",
+		"b/b.go": "package b\n\nvar B int = \"one\"\n",
+	}, "./a")
+
+	results, broken := build.Check(program)
+	if len(results) != 1 || len(results[0].Issues) != 1 {
+		t.Fatal("A broken dependency must be reported:", results)
+	}
+	issue := results[0].Issues[0]
+	if issue.Path != filepath.Join("a", "a.go") || issue.Line != 6 || issue.Severity != lint.SeverityError {
+		t.Error("The broken dependency must be reported as an error where it is imported:", issue)
+	}
+	if !strings.Contains(issue.Message, "example.com/nanny/b") || !strings.Contains(issue.Message, filepath.Join("b", "b.go")+":3:13") {
+		t.Error("The message must name the dependency and its error:", issue.Message)
+	}
+	if !broken.Packages["example.com/nanny/a"] {
+		t.Error("A package importing broken code is broken:", broken.Packages)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	program := load(t, map[string]string{
+		"ok/ok.go":         "package ok\n",
+		"broken/broken.go": "package broken\n\nvar x int = \"one\"\n",
+	})
+	_, broken := build.Check(program)
+	typed := func(name string, scope linters.Scope) linters.Linter {
+		return linters.Linter{Name: name, Scope: scope, NeedsTypes: true}
+	}
+	tasks := []lint.Task{
+		{Linter: typed("files", linters.ScopeFile), Targets: []string{"ok/ok.go", "broken/broken.go"}},
+		{Linter: typed("dir", linters.ScopeDir), Targets: []string{"./broken"}},
+		{Linter: typed("dir", linters.ScopeDir), Targets: []string{"./ok"}},
+		{Linter: typed("packages", linters.ScopePackages), Targets: []string{"example.com/nanny/broken", "example.com/nanny/ok"}},
+		{Linter: typed("recursive", linters.ScopeRecursive), Targets: []string{"./"}},
+		{Linter: linters.Linter{Name: "untyped", Scope: linters.ScopeDir}, Targets: []string{"./broken"}},
+	}
+
+	kept, skipped := broken.Skip(tasks)
+	if len(kept) != 4 || len(skipped) != 2 {
+		t.Fatal("Unexpected tasks:", kept, skipped)
+	}
+	if targets := kept[0].Targets; len(targets) != 1 || targets[0] != "ok/ok.go" {
+		t.Error("Files of broken packages must be dropped:", targets)
+	}
+	if targets := kept[1].Targets; len(targets) != 1 || targets[0] != "./ok" {
+		t.Error("Dirs that compile must be kept:", targets)
+	}
+	if targets := kept[2].Targets; len(targets) != 1 || targets[0] != "example.com/nanny/ok" {
+		t.Error("Broken packages must be dropped:", targets)
+	}
+	if kept[3].Linter.Name != "untyped" {
+		t.Error("Linters that need no types must run on broken code:", kept[3])
+	}
+	for _, result := range skipped {
+		if !result.Skipped || (result.Task.Linter.Name != "dir" && result.Task.Linter.Name != "recursive") {
+			t.Error("Unexpected skipped result:", result)
+		}
+	}
+}
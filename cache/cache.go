@@ -0,0 +1,192 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+//Package cache stores the issues found by the linters, so code that did not
+//change since the last run is not linted again
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lagarciag/codenanny/lint"
+	log "github.com/sirupsen/logrus"
+)
+
+//statsFile keeps the hit and miss counters in the cache dir
+const statsFile = "stats.json"
+
+//entriesDir holds the cached entries, split in sub dirs by the first byte of their key
+const entriesDir = "entries"
+
+//Key identifies the inputs of a linter run
+type Key string
+
+//NewKey hashes parts into a key, the parts are length prefixed so they can't run into each other
+func NewKey(parts ...string) Key {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d:%s;", len(part), part)
+	}
+	return Key(hex.EncodeToString(h.Sum(nil)))
+}
+
+//Cache is a content addressed store of issues
+type Cache struct {
+	Dir string
+}
+
+//Stats describes the content and use of a cache
+type Stats struct {
+	Dir     string `json:"-"`
+	Entries int    `json:"-"`
+	Size    int64  `json:"-"`
+	Hits    int64  `json:"hits"`
+	Misses  int64  `json:"misses"`
+}
+
+//DefaultDir returns the codenanny dir in the user cache dir
+func DefaultDir() (dir string, err error) {
+	if dir, err = os.UserCacheDir(); err != nil {
+		return dir, err
+	}
+	return filepath.Join(dir, "codenanny"), nil
+}
+
+//Open returns the cache stored in dir, creating dir if needed
+func Open(dir string) (c *Cache, err error) {
+	if err = os.MkdirAll(filepath.Join(dir, entriesDir), 0755); err != nil {
+		return nil, fmt.Errorf("could not create cache dir:%s", err.Error())
+	}
+	return &Cache{Dir: dir}, nil
+}
+
+//path returns the file of the entry of key
+func (c *Cache) path(key Key) string {
+	return filepath.Join(c.Dir, entriesDir, string(key[:2]), string(key)+".json")
+}
+
+//Get returns the issues stored under key
+func (c *Cache) Get(key Key) (issues []lint.Issue, found bool) {
+	data, err := ioutil.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	if err = json.Unmarshal(data, &issues); err != nil {
+		log.Debug("Dropping corrupted cache entry:", key)
+		return nil, false
+	}
+	return issues, true
+}
+
+//Put stores issues under key. The entry is written to a temporary file
+//first, so concurrent runs never read half written entries.
+func (c *Cache) Put(key Key, issues []lint.Issue) (err error) {
+	if issues == nil {
+		issues = []lint.Issue{}
+	}
+	data, err := json.Marshal(issues)
+	if err != nil {
+		return err
+	}
+	path := c.path(key)
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+//Clean removes every entry and the counters
+func (c *Cache) Clean() (err error) {
+	if err = os.RemoveAll(filepath.Join(c.Dir, entriesDir)); err != nil {
+		return err
+	}
+	if err = os.Remove(filepath.Join(c.Dir, statsFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.MkdirAll(filepath.Join(c.Dir, entriesDir), 0755)
+}
+
+//Record adds the hits and misses of a run to the counters.
+//Runs at the same time may lose counts, the counters are only informative.
+func (c *Cache) Record(hits, misses int) (err error) {
+	stats := c.counters()
+	stats.Hits += int64(hits)
+	stats.Misses += int64(misses)
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(c.Dir, statsFile), data, 0644)
+}
+
+//counters reads the hit and miss counters
+func (c *Cache) counters() (stats Stats) {
+	data, err := ioutil.ReadFile(filepath.Join(c.Dir, statsFile))
+	if err == nil {
+		err = json.Unmarshal(data, &stats)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		log.Debug("Resetting cache counters:", err)
+	}
+	return stats
+}
+
+//Stats returns the number and size of the entries and the counters
+func (c *Cache) Stats() (stats Stats, err error) {
+	stats = c.counters()
+	stats.Dir = c.Dir
+	err = filepath.Walk(filepath.Join(c.Dir, entriesDir), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".json") {
+			stats.Entries++
+			stats.Size += info.Size()
+		}
+		return nil
+	})
+	return stats, err
+}
+
+//HitRate returns the percentage of lookups found in the cache
+func (s Stats) HitRate() string {
+	if s.Hits+s.Misses == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(float64(s.Hits)*100/float64(s.Hits+s.Misses), 'f', 1, 64) + "%"
+}
@@ -0,0 +1,74 @@
+package cache_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/lagarciag/codenanny/cache"
+	"github.com/lagarciag/codenanny/lint"
+)
+
+func TestMain(t *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	formatter := &log.TextFormatter{}
+	formatter.ForceColors = true
+	formatter.DisableTimestamp = true
+	log.SetFormatter(formatter)
+	v := t.Run()
+	os.Exit(v)
+
+}
+
+func TestCache(t *testing.T) {
+	c, err := cache.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.NewKey("ab", "c") == cache.NewKey("a", "bc") {
+		t.Error("Key parts must not run into each other")
+	}
+
+	key := cache.NewKey("vet", "lint/lint.go")
+	if _, found := c.Get(key); found {
+		t.Error("Empty cache must miss")
+	}
+	issues := []lint.Issue{{Linter: "vet", Path: "lint/lint.go", Line: 3, Message: "unreachable code",
+		Severity: lint.SeverityError, Fixes: []lint.Fix{{Message: "remove", Edits: []lint.Edit{{Path: "lint/lint.go", Start: 1, End: 4}}}}}}
+	if err = c.Put(key, issues); err != nil {
+		t.Fatal(err)
+	}
+	if cachedIssues, found := c.Get(key); !found || !reflect.DeepEqual(cachedIssues, issues) {
+		t.Error("Stored issues must be returned:", cachedIssues)
+	}
+	clean := cache.NewKey("vet", "cmd/root.go")
+	if err = c.Put(clean, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cachedIssues, found := c.Get(clean); !found || len(cachedIssues) != 0 {
+		t.Error("Clean runs must be cached too:", cachedIssues, found)
+	}
+
+	if err = c.Record(3, 1); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 2 || stats.Size == 0 || stats.Hits != 3 || stats.Misses != 1 || stats.HitRate() != "75.0%" {
+		t.Error("Unexpected stats:", stats)
+	}
+
+	if err = c.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := c.Get(key); found {
+		t.Error("Clean must drop every entry")
+	}
+	if stats, _ = c.Stats(); stats.Entries != 0 || stats.Hits != 0 {
+		t.Error("Clean must reset the stats:", stats)
+	}
+}
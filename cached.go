@@ -0,0 +1,425 @@
+// Copyright © 2016 Luis Garcia <luis.a.garcia@hpe.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package codenanny
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/lagarciag/codenanny/cache"
+	"github.com/lagarciag/codenanny/config"
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/linters"
+	"github.com/lagarciag/codenanny/loader"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/tools/go/packages"
+)
+
+//resultCache replays the issues of the linter runs whose inputs did not change.
+//A run is cached per target, so a linter only runs again on the files,
+//packages or dirs that changed, or whose dependencies did.
+type resultCache struct {
+	cache   *cache.Cache
+	runner  *lint.Runner
+	program *loader.Program
+	//base hashes the inputs of every run: the codenanny binary, its version, the go version, go.mod and go.sum
+	base  string
+	tools map[string]string
+	files map[string]string
+	pkgs  map[string]string
+	//roots indexes the root packages of program by import path
+	roots map[string]*packages.Package
+	hits  int
+	miss  int
+}
+
+//newResultCache returns a result cache for the runs of runner on program
+func newResultCache(c *cache.Cache, runner *lint.Runner, program *loader.Program) (rc *resultCache) {
+	rc = &resultCache{
+		cache:   c,
+		runner:  runner,
+		program: program,
+		tools:   make(map[string]string),
+		files:   make(map[string]string),
+		pkgs:    make(map[string]string),
+		roots:   make(map[string]*packages.Package),
+	}
+	for _, pkg := range program.Roots() {
+		rc.roots[pkg.PkgPath] = pkg
+	}
+	goTool, _ := toolIdentity("go")
+	//The version is not bumped on every change of the builtin linters nor of the parsing of their output
+	self, _ := os.Executable()
+	selfTool, _ := toolIdentity(self)
+	rc.base = string(cache.NewKey(config.Version, selfTool, runtime.Version(), goTool,
+		rc.fileHash(filepath.Join(runner.Root, "go.mod")), rc.fileHash(filepath.Join(runner.Root, "go.sum"))))
+	return rc
+}
+
+//toolIdentity identifies the installed version of command by its path, size and time
+func toolIdentity(command string) (identity string, err error) {
+	path, err := exec.LookPath(command)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano()), nil
+}
+
+//linterHash hashes what decides the output of linter: its tool, declaration and configuration
+func (rc *resultCache) linterHash(linter linters.Linter) (hash string, ok bool) {
+	if hash, found := rc.tools[linter.Name]; found {
+		return hash, hash != ""
+	}
+	command := linter.Command
+	if linter.Analyzer != nil {
+		//Analyzers are built in codenanny
+		command, _ = os.Executable()
+	}
+	tool, err := toolIdentity(command)
+	if err != nil {
+		log.Debugf("Not caching %s:%s", linter.Name, err.Error())
+		rc.tools[linter.Name] = ""
+		return "", false
+	}
+	declaration, err := json.Marshal(struct {
+		Name        string
+		Tool        string
+		Scope       linters.Scope
+		Args        []string
+		FixArgs     []string
+		PreviewArgs []string
+		Pattern     string
+		Defaults    map[string]string
+		Settings    map[string]string
+		Ignore      []config.IgnorePattern
+	}{
+		linter.Name, tool, linter.Scope, linter.Args, linter.FixArgs, linter.PreviewArgs, linter.Pattern, linter.Defaults,
+		rc.runner.Config.Settings[linter.Name], rc.runner.Config.IgnorePattern[linter.Name],
+	})
+	if err != nil {
+		rc.tools[linter.Name] = ""
+		return "", false
+	}
+	hash = string(cache.NewKey(string(declaration)))
+	rc.tools[linter.Name] = hash
+	return hash, true
+}
+
+//fileHash hashes the content of path, a missing file has a hash too
+func (rc *resultCache) fileHash(path string) string {
+	if hash, found := rc.files[path]; found {
+		return hash
+	}
+	hash := "missing"
+	if data, err := ioutil.ReadFile(path); err == nil {
+		hash = string(cache.NewKey(string(data)))
+	}
+	rc.files[path] = hash
+	return hash
+}
+
+//inRoot tells if pkg is part of the linted repo
+func (rc *resultCache) inRoot(pkg *packages.Package) bool {
+	return len(pkg.GoFiles) > 0 && strings.HasPrefix(pkg.GoFiles[0], rc.runner.Root+string(filepath.Separator))
+}
+
+//pkgHash hashes the files of pkg and of the packages it depends on.
+//Packages out of the repo are identified by their module version, go.sum and the go version.
+func (rc *resultCache) pkgHash(pkg *packages.Package) string {
+	if hash, found := rc.pkgs[pkg.ID]; found {
+		return hash
+	}
+	parts := []string{pkg.ID}
+	if !rc.inRoot(pkg) {
+		if pkg.Module != nil {
+			parts = append(parts, pkg.Module.Path, pkg.Module.Version)
+		}
+	} else {
+		for _, file := range append(append([]string{}, pkg.GoFiles...), pkg.OtherFiles...) {
+			parts = append(parts, file, rc.fileHash(file))
+		}
+		imports := make([]string, 0, len(pkg.Imports))
+		for path := range pkg.Imports {
+			imports = append(imports, path)
+		}
+		sort.Strings(imports)
+		for _, path := range imports {
+			parts = append(parts, path, rc.pkgHash(pkg.Imports[path]))
+		}
+	}
+	hash := string(cache.NewKey(parts...))
+	rc.pkgs[pkg.ID] = hash
+	return hash
+}
+
+//pkgDir returns the dir of pkg relative to the root
+func (rc *resultCache) pkgDir(pkg *packages.Package) string {
+	if len(pkg.GoFiles) == 0 {
+		return ""
+	}
+	dir, err := filepath.Rel(rc.runner.Root, filepath.Dir(pkg.GoFiles[0]))
+	if err != nil {
+		return ""
+	}
+	return dir
+}
+
+//dirHash hashes the go files of dir and the packages found there
+func (rc *resultCache) dirHash(dir string, recursive bool) (hash string, ok bool) {
+	var parts []string
+	absDir := filepath.Join(rc.runner.Root, dir)
+	err := filepath.Walk(absDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path != absDir && !recursive {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".go") {
+			parts = append(parts, path, rc.fileHash(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", false
+	}
+	if !recursive {
+		dir = filepath.Clean(dir)
+		for _, pkg := range rc.program.Roots() {
+			if rc.pkgDir(pkg) == dir {
+				parts = append(parts, rc.pkgHash(pkg))
+			}
+		}
+	}
+	return string(cache.NewKey(parts...)), true
+}
+
+//targetHash hashes what a linter reads when run on target
+func (rc *resultCache) targetHash(scope linters.Scope, target string) (hash string, ok bool) {
+	switch scope {
+	case linters.ScopeFile:
+		return rc.fileHash(filepath.Join(rc.runner.Root, target)), true
+	case linters.ScopePackage, linters.ScopePackages:
+		pkg, found := rc.roots[target]
+		if !found {
+			return "", false
+		}
+		//The files of the external test package are linted with the package
+		if xtest, found := rc.roots[target+"_test"]; found {
+			return string(cache.NewKey(rc.pkgHash(pkg), rc.pkgHash(xtest))), true
+		}
+		return rc.pkgHash(pkg), true
+	case linters.ScopeDir:
+		return rc.dirHash(target, false)
+	case linters.ScopeRecursive:
+		return rc.dirHash(target, true)
+	}
+	return "", false
+}
+
+//key returns the cache key of running linter on target
+func (rc *resultCache) key(linter linters.Linter, target string) (key cache.Key, ok bool) {
+	linterHash, ok := rc.linterHash(linter)
+	if !ok {
+		return key, false
+	}
+	targetHash, ok := rc.targetHash(linter.Scope, target)
+	if !ok {
+		return key, false
+	}
+	return cache.NewKey(rc.base, linterHash, target, targetHash), true
+}
+
+//lookup replays the cached targets of tasks. It returns the tasks left to
+//run, with the targets not found in the cache, and the keys to store their results under.
+func (rc *resultCache) lookup(tasks []lint.Task) (run []lint.Task, keys []map[string]cache.Key, cached []lint.Result) {
+	for _, task := range tasks {
+		if len(task.Targets) == 0 {
+			run = append(run, task)
+			keys = append(keys, nil)
+			continue
+		}
+		var missed, hitTargets []string
+		var hitIssues []lint.Issue
+		taskKeys := make(map[string]cache.Key)
+		for _, target := range task.Targets {
+			key, ok := rc.key(task.Linter, target)
+			if ok {
+				if issues, found := rc.cache.Get(key); found {
+					rc.hits++
+					hitTargets = append(hitTargets, target)
+					hitIssues = append(hitIssues, issues...)
+					continue
+				}
+				taskKeys[target] = key
+			}
+			rc.miss++
+			missed = append(missed, target)
+		}
+		if len(hitTargets) > 0 {
+			cached = append(cached, lint.Result{
+				Task:   lint.Task{Linter: task.Linter, Targets: hitTargets},
+				Issues: hitIssues,
+				Cached: true,
+			})
+		}
+		if len(missed) > 0 {
+			run = append(run, lint.Task{Linter: task.Linter, Targets: missed})
+			keys = append(keys, taskKeys)
+		}
+	}
+	log.Debugf("Cache: %d targets replayed, %d to lint", rc.hits, rc.miss)
+	return run, keys, cached
+}
+
+//owner returns the target of task an issue was found in
+func (rc *resultCache) owner(task lint.Task, issue lint.Issue) (target string, found bool) {
+	if len(task.Targets) == 1 {
+		return task.Targets[0], true
+	}
+	for _, target := range task.Targets {
+		switch task.Linter.Scope {
+		case linters.ScopeFile:
+			found = filepath.Clean(target) == issue.Path
+		case linters.ScopePackage, linters.ScopePackages:
+			pkg, known := rc.roots[target]
+			found = known && issue.Path != "" && rc.pkgDir(pkg) == filepath.Dir(issue.Path)
+		case linters.ScopeDir:
+			found = issue.Path != "" && filepath.Clean(target) == filepath.Dir(issue.Path)
+		}
+		if found {
+			return target, true
+		}
+	}
+	return "", false
+}
+
+//store saves the issues of results per target. Failed runs are not
+//stored, nor runs with issues that can't be told apart by target.
+func (rc *resultCache) store(results []lint.Result, keys []map[string]cache.Key) {
+	for id, result := range results {
+		if result.Err != nil || len(keys[id]) == 0 {
+			continue
+		}
+		byTarget := make(map[string][]lint.Issue)
+		split := true
+		for _, issue := range result.Issues {
+			target, found := rc.owner(result.Task, issue)
+			if !found {
+				split = false
+				break
+			}
+			byTarget[target] = append(byTarget[target], issue)
+		}
+		if !split {
+			log.Debugf("Not caching %s, its issues can't be split by target", result.Task.Linter.Name)
+			continue
+		}
+		for target, key := range keys[id] {
+			rc.put(key, byTarget[target])
+		}
+	}
+}
+
+//put stores issues under key, a cache that can't be written does not fail the run
+func (rc *resultCache) put(key cache.Key, issues []lint.Issue) {
+	if err := rc.cache.Put(key, issues); err != nil {
+		log.Warn("Could not write cache entry:", err)
+	}
+}
+
+//analysisKey returns the cache key of running an analysis linter on pkg
+func (rc *resultCache) analysisKey(linter linters.Linter, pkg *packages.Package) (key cache.Key, ok bool) {
+	linterHash, ok := rc.linterHash(linter)
+	if !ok {
+		return key, false
+	}
+	return cache.NewKey(rc.base, linterHash, pkg.ID, rc.pkgHash(pkg)), true
+}
+
+//lookupAnalysis replays the analysis of the packages every analyzer in list
+//has in the cache, and returns the packages left to analyze.
+func (rc *resultCache) lookupAnalysis(list []linters.Linter, roots []*packages.Package) (run []*packages.Package, cached []lint.Result) {
+	for _, pkg := range roots {
+		var pkgResults []lint.Result
+		for _, linter := range list {
+			key, ok := rc.analysisKey(linter, pkg)
+			if !ok {
+				break
+			}
+			issues, found := rc.cache.Get(key)
+			if !found {
+				break
+			}
+			pkgResults = append(pkgResults, lint.Result{
+				Task:   lint.Task{Linter: linter, Targets: []string{pkg.PkgPath}},
+				Issues: issues,
+				Cached: true,
+			})
+		}
+		if len(pkgResults) == len(list) {
+			rc.hits++
+			cached = append(cached, pkgResults...)
+			continue
+		}
+		rc.miss++
+		run = append(run, pkg)
+	}
+	return run, cached
+}
+
+//storeAnalysis saves the issues of the analysis results of roots
+func (rc *resultCache) storeAnalysis(results []lint.Result, roots []*packages.Package) {
+	byPath := make(map[string]*packages.Package, len(roots))
+	for _, pkg := range roots {
+		byPath[pkg.PkgPath] = pkg
+	}
+	for _, result := range results {
+		if result.Err != nil || result.Task.Linter.Analyzer == nil || len(result.Task.Targets) != 1 {
+			continue
+		}
+		pkg, found := byPath[result.Task.Targets[0]]
+		if !found {
+			continue
+		}
+		if key, ok := rc.analysisKey(result.Task.Linter, pkg); ok {
+			rc.put(key, result.Issues)
+		}
+	}
+}
+
+//record adds the hits and misses of the run to the cache counters
+func (rc *resultCache) record() {
+	if err := rc.cache.Record(rc.hits, rc.miss); err != nil {
+		log.Debug("Could not record cache stats:", err)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright © 2016 Luis Garcia <luis.a.garcia@hpe.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lagarciag/codenanny"
+	"github.com/lagarciag/codenanny/baseline"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var baselinePath string
+
+// baselineCmd represents the baseline command
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "manages the baseline of known issues",
+	Long: `command baseline manages the baseline file, committed with the code: lint and lintdir
+leave out the issues it lists, so only the new ones fail the run. The file is set with --baseline.`,
+}
+
+// baselineCreateCmd represents the baseline create command
+var baselineCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "writes the current issues to the baseline file",
+	Long:  `command create lints the --path dir and writes every issue found to the baseline file, replacing it`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report, path, err := lintForBaseline()
+		if err != nil {
+			return err
+		}
+		known := baseline.New(report.Root, report.Issues)
+		if err = known.Save(path); err != nil {
+			return toolError(fmt.Errorf("could not write the baseline:%s", err.Error()))
+		}
+		log.Infof("Baseline %s written with %d issues", path, known.Len())
+		return nil
+	},
+}
+
+// baselinePruneCmd represents the baseline prune command
+var baselinePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "drops the fixed issues from the baseline file",
+	Long: `command prune lints the --path dir and drops from the baseline file the issues of that dir
+that are not found anymore. New issues are not added.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report, path, err := lintForBaseline()
+		if err != nil {
+			return err
+		}
+		known, err := baseline.Load(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return configError(fmt.Errorf("no baseline to prune, run baseline create first:%s", err.Error()))
+			}
+			return configError(err)
+		}
+		dir, err := rootRelative(report.Root, baselinePath)
+		if err != nil {
+			return configError(err)
+		}
+		pruned := known.Prune(report.Root, dir, report.Issues)
+		if err = known.Save(path); err != nil {
+			return toolError(fmt.Errorf("could not write the baseline:%s", err.Error()))
+		}
+		log.Infof("Baseline %s pruned of %d fixed issues, %d left", path, pruned, known.Len())
+		return nil
+	},
+}
+
+//lintForBaseline lints the --path dir without baseline and returns the report with the path of the baseline file.
+//A run that did not complete would leave issues out of the baseline, it is an error.
+func lintForBaseline() (report *codenanny.Report, path string, err error) {
+	if verbose {
+		log.SetLevel(log.DebugLevel)
+		log.Debug("verbose mode enabled")
+	}
+	if baselineFile == "" {
+		return nil, "", configError(errors.New("--baseline must name the baseline file"))
+	}
+	opts := newOptions()
+	opts.Dir = baselinePath
+	opts.Baseline = ""
+	opts.Output = nil
+
+	ctx, cancel := signalContext()
+	defer cancel()
+	report, err = codenanny.Run(ctx, opts)
+	if report == nil {
+		return nil, "", setupError(err)
+	}
+	logToolErrors(report.ToolErrors())
+	switch report.Verdict {
+	case codenanny.VerdictInterrupted:
+		return nil, "", errInterrupted
+	case codenanny.VerdictError:
+		return nil, "", toolError(errors.New("Linters failed, the baseline is left as it was"))
+	}
+	if err != nil {
+		return nil, "", toolError(err)
+	}
+
+	path = baselineFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(report.Root, path)
+	}
+	return report, path, nil
+}
+
+//rootRelative returns path relative to root, relative paths being taken from root as the linted dir is
+func rootRelative(root, path string) (rel string, err error) {
+	if !filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+	//The root is found by git, with the symbolic links resolved
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	rel, err = filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not in the repo %s", path, root)
+	}
+	return rel, nil
+}
+
+func init() {
+	RootCmd.AddCommand(baselineCmd)
+	baselineCmd.AddCommand(baselineCreateCmd)
+	baselineCmd.AddCommand(baselinePruneCmd)
+	baselineCmd.PersistentFlags().StringVarP(&baselinePath, "path", "p", "./", "path to lint")
+}
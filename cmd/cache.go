@@ -0,0 +1,95 @@
+// Copyright © 2016 Luis Garcia <luis.a.garcia@hpe.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lagarciag/codenanny/cache"
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "manages the cache of linter results",
+	Long:  `command cache manages the cache that keeps linters from running again on unchanged code`,
+}
+
+// cacheCleanCmd represents the cache clean command
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "removes every cached result",
+	Long:  `command clean removes every cached result, the next run lints everything again`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+		if err = c.Clean(); err != nil {
+			return toolError(fmt.Errorf("could not clean the cache:%s", err.Error()))
+		}
+		fmt.Println("Cleaned", c.Dir)
+		return nil
+	},
+}
+
+// cacheStatsCmd represents the cache stats command
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "shows the size and hit rate of the cache",
+	Long:  `command stats shows where the cache is, its size and how many lookups it answered`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := openCache()
+		if err != nil {
+			return err
+		}
+		stats, err := c.Stats()
+		if err != nil {
+			return toolError(fmt.Errorf("could not read the cache:%s", err.Error()))
+		}
+		fmt.Println("Dir:     ", stats.Dir)
+		fmt.Println("Entries: ", stats.Entries)
+		fmt.Printf("Size:     %.1f KiB\n", float64(stats.Size)/1024)
+		fmt.Println("Hits:    ", stats.Hits)
+		fmt.Println("Misses:  ", stats.Misses)
+		fmt.Println("Hit rate:", stats.HitRate())
+		return nil
+	},
+}
+
+//openCache opens the cache used by the lint commands
+func openCache() (c *cache.Cache, err error) {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil, toolError(fmt.Errorf("no user cache dir:%s", err.Error()))
+	}
+	if c, err = cache.Open(dir); err != nil {
+		return nil, toolError(err)
+	}
+	return c, nil
+}
+
+func init() {
+	RootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+}
@@ -25,7 +25,6 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/lagarciag/codenanny/cmd"
 	"github.com/lagarciag/codenanny/config"
-	"github.com/lagarciag/codenanny/lint"
 )
 
 func TestMain(t *testing.M) {
@@ -41,14 +40,16 @@ func TestMain(t *testing.M) {
 
 func TestNannyNoErrors(t *testing.T) {
 	log.Debug("TestNanny run")
-	if err := config.LoadConfig(); err != nil {
-		t.Error("Error loading configuration")
+	rootPath, err := config.GitRoot(".")
+	if err != nil {
+		t.Error("Could not find git root")
 	}
-	if err := lint.ChgDirToGitRootPath(); err != nil {
-		t.Error("Chage dir to git root returned error")
+	conf, err := config.Load(rootPath)
+	if err != nil {
+		t.Error("Error loading configuration")
 	}
 
-	if err := cmd.DoLintForTesting(config.GlobalConfig); err != nil {
+	if err := cmd.DoLintForTesting(conf); err != nil {
 		t.Error(err)
 	}
 
@@ -19,36 +19,15 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
-	"os/exec"
-	"strings"
+	"context"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/lagarciag/codenanny"
 	"github.com/lagarciag/codenanny/config"
 )
 
 //DoLintForTesting is a service function for implementing codenanny tests
 func DoLintForTesting(conf config.CodeNannyConfig) (err error) {
-	config.GlobalConfig = conf
-
-	//Find out what the Root Path is
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	tmpRootPath, err := cmd.Output()
-	if err != nil {
-		err = fmt.Errorf("Could not find git root for this repo:%s", err.Error())
-		log.Errorf("Is this a git repo???:%s", err.Error())
-		return err
-	}
-
-	//Trim return character
-	rootPath := strings.TrimSpace(string(tmpRootPath))
-	err = os.Chdir(rootPath)
-	if err != nil {
-		err = fmt.Errorf("Could not change to repos root dir:%s", err.Error())
-		return err
-	}
 	log.Debug("Dolint for testing...")
-	err = Lintdir("./")
-	return err
+	return runNanny(context.Background(), codenanny.Options{Config: &conf, Dir: "./"})
 }
@@ -0,0 +1,89 @@
+// Copyright © 2016 Luis Garcia <luis.a.garcia@hpe.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+)
+
+//Exit codes of codenanny, they are stable so CI can tell the outcomes apart
+const (
+	//exitClean means every linter ran and found nothing that fails the run
+	exitClean = 0
+	//exitFindings means the linters found issues that fail the run
+	exitFindings = 1
+	//exitToolError means a linter or a tool codenanny needs failed, crashed or timed out
+	exitToolError = 2
+	//exitConfigError means the .codenanny file, the flags or the arguments are invalid
+	exitConfigError = 3
+	//exitInternal means codenanny itself failed
+	exitInternal = 70
+	//exitInterrupted means the run was stopped by SIGINT or SIGTERM
+	exitInterrupted = 130
+)
+
+//errInterrupted is returned when the run was stopped by a signal
+var errInterrupted = errors.New("interrupted")
+
+//exitError is an error that ends codenanny with code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+//findingsError returns an error exiting with exitFindings
+func findingsError(format string, args ...interface{}) error {
+	return &exitError{code: exitFindings, err: fmt.Errorf(format, args...)}
+}
+
+//toolError returns an error exiting with exitToolError
+func toolError(err error) error {
+	return &exitError{code: exitToolError, err: err}
+}
+
+//configError returns an error exiting with exitConfigError
+func configError(err error) error {
+	return &exitError{code: exitConfigError, err: err}
+}
+
+//exitCode returns the exit code for the error returned by a command.
+//Errors that are not exit errors come from cobra and are usage errors.
+func exitCode(err error) int {
+	var exitErr *exitError
+	switch {
+	case err == nil:
+		return exitClean
+	case errors.Is(err, errInterrupted):
+		return exitInterrupted
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	}
+	return exitConfigError
+}
@@ -0,0 +1,87 @@
+// Copyright © 2016 Luis Garcia <luis.a.garcia@hpe.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+
+	"github.com/lagarciag/codenanny"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var fixPath string
+
+// fixCmd represents the fix command
+var fixCmd = &cobra.Command{
+	Use:   "fix [files]",
+	Short: "lets the fixers rewrite the files and prints the ones they changed",
+	Long: `runs gofmt -s, goimports, unconvert, misspell and the suggested fixes of the analyzers
+on the files passed as arguments, or on the go files found in the --path dir.
+The linters never write to the files unless fix or --fix is used.
+With --diff or --patch the files are left untouched and the changes are printed or saved as a patch.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if verbose {
+			log.SetLevel(log.DebugLevel)
+			log.Debug("verbose mode enabled")
+		}
+		opts := newOptions()
+		opts.Files = args
+		opts.Dir = fixPath
+		if opts.Diff {
+			//Only the diff goes to the standard output, so it can be piped to git apply
+			opts.Output = nil
+		}
+
+		ctx, cancel := signalContext()
+		defer cancel()
+		report, err := codenanny.Fix(ctx, opts)
+		if report == nil {
+			return setupError(err)
+		}
+		logToolErrors(report.ToolErrors())
+		if writeErr := writeDiff(report.Diff); writeErr != nil {
+			return writeErr
+		}
+
+		switch report.Verdict {
+		case codenanny.VerdictInterrupted:
+			log.Warnf("Interrupted after %s, %d files changed so far", report.Duration, len(report.Changed))
+			return errInterrupted
+		case codenanny.VerdictError:
+			return toolError(errors.New("Fixers failed"))
+		}
+		if err != nil {
+			return toolError(err)
+		}
+		if opts.Diff {
+			log.Infof("Fixers would change %d files", len(report.Changed))
+			return nil
+		}
+		log.Infof("Fixers changed %d files", len(report.Changed))
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(fixCmd)
+	fixCmd.Flags().StringVarP(&fixPath, "path", "p", "./", "path to fix when no file is passed")
+}
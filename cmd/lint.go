@@ -22,19 +22,16 @@ package cmd
 
 import (
 	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
-
 	"regexp"
 
-	"fmt"
-
-	"sync"
-
 	log "github.com/sirupsen/logrus"
-	"github.com/lagarciag/codenanny/config"
-	"github.com/lagarciag/codenanny/installer"
+	"github.com/lagarciag/codenanny"
 	"github.com/lagarciag/codenanny/lint"
-	"github.com/lagarciag/codenanny/parser"
 	"github.com/spf13/cobra"
 )
 
@@ -45,25 +42,22 @@ var lintCmd = &cobra.Command{
 	Use:   "lint",
 	Short: "Run the linters",
 	Long:  `Runs the linters`,
-	Run: func(cmd *cobra.Command, args []string) {
-		// TODO: Work your own magic here
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if argList == "" {
-			log.Fatal("--list flag must be set and point to a list of files that need to be linted")
+			return configError(errors.New("--list flag must be set and point to a list of files that need to be linted"))
 		}
 		if verbose {
 			log.SetLevel(log.DebugLevel)
 			log.Debug("verbose mode enabled")
 		}
-		if err := config.LoadConfig(); err != nil {
-			log.Fatal("error loading config:", err)
-		}
-		dirSlice := parseListFromArgs()
+		opts := newOptions()
+		opts.Files = parseListFromArgs()
 
-		log.Debug("DIR SLICE:", dirSlice)
+		log.Debug("DIR SLICE:", opts.Files)
 
-		if err := doLint(dirSlice); err != nil {
-			log.Fatal("Lint found errors")
-		}
+		ctx, cancel := signalContext()
+		defer cancel()
+		return runNanny(ctx, opts)
 	},
 }
 
@@ -102,109 +96,81 @@ func parseListFromArgs() (listSlice []string) {
 	return listSlice
 }
 
-func doLint(listSlice []string) (err error) {
-	var singPkgErr error
-	var multiPkgErr error
-	var dirCheckErr error
-	var dirCheckRecErr error
-
-	listSlice, _ = filterList(listSlice)
-
-	if err = installer.CheckExternalDependencies(); err != nil {
-		return err
+//newOptions returns the options set by the command line flags
+func newOptions() (opts codenanny.Options) {
+	opts = codenanny.Options{
+		Jobs:     jobs,
+		Timeout:  timeoutFlag,
+		NoCache:  noCache,
+		Fix:      fixFlag,
+		Test:     testFlag,
+		Base:     baseFlag,
+		Baseline: baselineFile,
+		Diff:     diffFlag || patchFile != "",
+		FailOn:   lint.Severity(failOn),
+		Output:   os.Stdout,
 	}
-
-	// check if there are disabled tools in the configuration
-	for key := range config.GlobalConfig.Disabled {
-		log.Debug("CONFIG DISABLED:", key)
-		if config.GlobalConfig.Disabled[key] {
-			installer.DisabledTool[key] = true
-		}
+	if diffFlag {
+		//The findings go to the standard error, the standard output is left to the diff
+		opts.Output = os.Stderr
 	}
+	return opts
+}
 
-	dirList, pkag, err := parser.Parse(listSlice)
-
-	log.Debug("Packages:", pkag)
-	log.Debug("dirList:", dirList)
-
-	if err != nil {
-		log.Error(err)
-		return err
+//runNanny runs codenanny and logs the linters that could not run.
+//It returns an error, carrying the exit code, unless every linter ran and found nothing.
+func runNanny(ctx context.Context, opts codenanny.Options) (err error) {
+	report, err := codenanny.Run(ctx, opts)
+	if report == nil {
+		return setupError(err)
 	}
-
-	wg := &sync.WaitGroup{}
-	wg.Add(4)
-
-	multiPackages := func() {
-		err = lint.CheckMultiPackages(pkag)
-		if err != nil {
-			multiPkgErr = fmt.Errorf("Multi packages checker failed:%s", err.Error())
-			log.Error(multiPkgErr.Error())
-		}
-		wg.Done()
+	for _, path := range report.Fixed {
+		log.Info("Fixed:", path)
 	}
-
-	singlePackages := func() {
-		err = lint.CheckSinglePackages(pkag)
-		if err != nil {
-			singPkgErr = fmt.Errorf("Single packages checker failed:%s", err.Error())
-			log.Error(singPkgErr.Error())
-		}
-		wg.Done()
+	if writeErr := writeDiff(report.Diff); writeErr != nil {
+		return writeErr
 	}
 
-	checkRecDirs := func() {
-		err = lint.CheckRecursiveDirs(dirList)
-		if err != nil {
-			dirCheckRecErr = fmt.Errorf("Directory recursive checker failed:,%s", err.Error())
-			log.Error(dirCheckRecErr.Error())
+	for _, linter := range report.Linters {
+		switch linter.Status {
+		case codenanny.StatusFailed, codenanny.StatusTimeout:
+			for _, linterErr := range linter.Errors {
+				log.Errorf("%s checker failed:%s", linter.Name, linterErr.Error())
+			}
+		case codenanny.StatusSkipped:
+			log.Warnf("%s skipped the code that does not compile", linter.Name)
 		}
-		wg.Done()
 	}
+	logToolErrors(report.ToolErrors())
 
-	checkDirs := func() {
-		err = lint.CheckMultiDirs(dirList)
-		if err != nil {
-			dirCheckErr = fmt.Errorf("Single dir checker failed:,%s", err.Error())
-			log.Error(dirCheckErr.Error())
-		}
-		wg.Done()
+	if tests := report.Tests; tests != (lint.TestSummary{}) {
+		log.Infof("Test packages: %d passed, %d failed, %d skipped. Tests: %d passed, %d failed, %d skipped",
+			tests.Packages.Passed, tests.Packages.Failed, tests.Packages.Skipped,
+			tests.Tests.Passed, tests.Tests.Failed, tests.Tests.Skipped)
 	}
-
-	go multiPackages()
-	go singlePackages()
-	go checkRecDirs()
-	go checkDirs()
-
-	wg.Wait()
-
-	if multiPkgErr != nil || singPkgErr != nil || dirCheckErr != nil || dirCheckRecErr != nil {
-		err = fmt.Errorf("Linters failed:%s", "")
+	if report.Baselined > 0 {
+		log.Infof("%d issues left out, the baseline lists them", report.Baselined)
+	}
+	if report.Suppressed > 0 {
+		log.Infof("%d issues suppressed by nanny:ignore directives", report.Suppressed)
+	}
+	if warnings, infos := report.Counts[lint.SeverityWarning], report.Counts[lint.SeverityInfo]; warnings+infos > 0 {
+		log.Warnf("%d warnings, %d infos", warnings, infos)
 	}
 
-	return err
-}
-
-func filterList(listSlice []string) (newListSlice []string, err error) {
-	tmpList := list.New()
-	for _, file := range listSlice {
-		match := false
-		if config.GlobalConfig.IgnorePath != "" {
-			match, _ = regexp.MatchString(config.GlobalConfig.IgnorePath, file)
-		}
-		if !match {
-			tmpList.PushBack(file)
-		} else {
-			log.Debug("Ignore path matched:", file)
-		}
+	switch report.Verdict {
+	case codenanny.VerdictInterrupted:
+		log.Warnf("Interrupted after %s, %d issues found so far", report.Duration, len(report.Issues))
+		return errInterrupted
+	case codenanny.VerdictError:
+		return toolError(errors.New("Linters failed"))
+	case codenanny.VerdictIssues:
+		return findingsError("Linters found %d issues, %d errors", len(report.Issues), report.Counts[lint.SeverityError])
 	}
-	newListSlice = make([]string, tmpList.Len())
-	count := 0
-	for e := tmpList.Front(); e != nil; e = e.Next() {
-		newListSlice[count] = e.Value.(string)
-		count++
+	if err != nil {
+		return toolError(err)
 	}
-	return newListSlice, err
+	return nil
 }
 
 func init() {
@@ -213,3 +179,36 @@ func init() {
 	lintCmd.Flags().StringVar(&argList, "list", "./", "list of files to process")
 
 }
+
+//setupError returns the error of a run that could not be set up with its exit code
+func setupError(err error) error {
+	var confErr *codenanny.ConfigError
+	if errors.As(err, &confErr) {
+		return configError(err)
+	}
+	return toolError(err)
+}
+
+//writeDiff prints diff with --diff and writes it to the --patch file
+func writeDiff(diff string) error {
+	if diffFlag {
+		fmt.Print(diff)
+	}
+	if patchFile != "" {
+		if err := ioutil.WriteFile(patchFile, []byte(diff), 0644); err != nil {
+			return toolError(fmt.Errorf("could not write the patch:%s", err.Error()))
+		}
+		log.Info("Patch written to ", patchFile)
+	}
+	return nil
+}
+
+//logToolErrors logs the tool errors with the raw output of the linters
+func logToolErrors(toolErrors []*lint.ToolError) {
+	for _, toolErr := range toolErrors {
+		log.Errorf("Tool error, %s", toolErr.Error())
+		if toolErr.Output != "" {
+			log.Errorf("%s output:\n%s", toolErr.Linter, toolErr.Output)
+		}
+	}
+}
@@ -21,9 +21,9 @@
 package cmd
 
 import (
+	"errors"
+
 	log "github.com/sirupsen/logrus"
-	"github.com/lagarciag/codenanny/config"
-	"github.com/lagarciag/codenanny/dirlister"
 	"github.com/spf13/cobra"
 )
 
@@ -34,42 +34,24 @@ var lintdirCmd = &cobra.Command{
 	Use:   "lintdir",
 	Short: "runs linters and code checkers on the provided dir using the -p flag",
 	Long:  `runs linters and code checkers on the provided dir using the -p flag`,
-	Run: func(cmd *cobra.Command, args []string) {
-		// TODO: Work your own magic here
+	RunE: func(cmd *cobra.Command, args []string) error {
 		if verbose {
 			log.SetLevel(log.DebugLevel)
 			log.Debug("verbose mode enabled")
 		}
 		if pathFlag == "" {
-			log.Fatal("you must define the --path flag for lintdir command")
-		}
-
-		if err := config.LoadConfig(); err != nil {
-			log.Fatal("error loading config:", err)
+			return configError(errors.New("you must define the --path flag for lintdir command"))
 		}
 
-		if err := Lintdir(pathFlag); err != nil {
-			log.Fatal("Lint dir found errors")
-		}
+		opts := newOptions()
+		opts.Dir = pathFlag
 
+		ctx, cancel := signalContext()
+		defer cancel()
+		return runNanny(ctx, opts)
 	},
 }
 
-//Lintdir is a helper function that creates a list of all the files found in the provided directory
-func Lintdir(path string) (err error) {
-	fileSlice, _, err := dirlister.ListDir(path)
-
-	//for _, dir := range fileSlice {
-	//	//log.Debug("DIR:", dir)
-	//}
-
-	if err != nil {
-		return err
-	}
-	err = doLint(fileSlice)
-	return err
-}
-
 func init() {
 	RootCmd.AddCommand(lintdirCmd)
 	lintdirCmd.PersistentFlags().StringVarP(&pathFlag, "path", "p", "./", "path to lint")
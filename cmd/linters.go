@@ -0,0 +1,49 @@
+// Copyright © 2016 Luis Garcia <luis.a.garcia@hpe.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lagarciag/codenanny/linters"
+	"github.com/spf13/cobra"
+)
+
+// lintersCmd represents the linters command
+var lintersCmd = &cobra.Command{
+	Use:   "linters",
+	Short: "lists the linters codenanny knows about",
+	Long:  `command linters lists every registered linter with its scope and whether it runs by default`,
+	Run: func(cmd *cobra.Command, args []string) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSCOPE\tENABLED\tFIXER\tINSTALL")
+		for _, l := range linters.All() {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%t\t%s\n", l.Name, l.Scope, l.Enabled, l.CanFix(), l.InstallPath)
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(lintersCmd)
+}
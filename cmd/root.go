@@ -21,9 +21,17 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
-
+	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lagarciag/codenanny/baseline"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/spf13/cobra"
@@ -32,12 +40,25 @@ import (
 
 var cfgFile string
 var verbose bool
+var jobs int
+var timeoutFlag time.Duration
+var noCache bool
+var failOn string
+var fixFlag bool
+var diffFlag bool
+var testFlag bool
+var patchFile string
+var baseFlag string
+var baselineFile string
 
 //RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Use:   "codenanny",
 	Short: "runs multiple linters",
 	Long:  `Runs multiple linters`,
+	//Errors are logged by Execute, which exits with the code matching the error
+	SilenceErrors: true,
+	SilenceUsage:  true,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//	Run: func(cmd *cobra.Command, args []string) { },
@@ -45,11 +66,18 @@ var RootCmd = &cobra.Command{
 
 // Execute adds all child commands to the root command sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// It exits with the code matching the outcome of the command, see exit.go.
 func Execute() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("codenanny internal error:%v\n%s", r, debug.Stack())
+			os.Exit(exitInternal)
+		}
+	}()
 
 	if err := RootCmd.Execute(); err != nil {
 		log.Error(err)
-		os.Exit(-1)
+		os.Exit(exitCode(err))
 	}
 	if verbose {
 		log.SetLevel(log.DebugLevel)
@@ -73,6 +101,47 @@ func init() {
 	// when this action is called directly.
 	RootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose mode")
+	RootCmd.PersistentFlags().DurationVar(&timeoutFlag, "timeout", 0, "time each linter is allowed to run, 0 means no limit")
+	RootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", runtime.GOMAXPROCS(0), "number of linters to run at the same time")
+	RootCmd.PersistentFlags().StringVar(&failOn, "fail-on", "error", "lowest severity that fails the run: error, warning or info")
+	RootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "lint everything again instead of replaying the results of unchanged code")
+	RootCmd.PersistentFlags().BoolVar(&testFlag, "test", false, "run go test on the linted packages, failing tests are reported as issues")
+	RootCmd.PersistentFlags().BoolVar(&fixFlag, "fix", false, "let the fixers rewrite the files before linting them")
+	RootCmd.PersistentFlags().BoolVar(&diffFlag, "diff", false, "print what the fixers would change as a unified diff, without writing the files")
+	RootCmd.PersistentFlags().StringVar(&baseFlag, "base", "", "git revision the coverage of the changed lines is computed against, default HEAD")
+	RootCmd.PersistentFlags().StringVar(&baselineFile, "baseline", baseline.DefaultFile, "baseline file of the known issues, relative to the repo root, empty for none")
+	RootCmd.PersistentFlags().StringVar(&patchFile, "patch", "", "write what the fixers would change to a patch file for git apply, without writing the files")
+}
+
+//signalContext returns a context cancelled on the first SIGINT or SIGTERM.
+//The signals are handled until the returned function is called, once the run is over: the linters run in
+//their own process groups, exiting on a second signal would leave them running.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case sig := <-signals:
+				if ctx.Err() == nil {
+					log.Warnf("Received %s, stopping the linters", sig)
+					cancel()
+				} else {
+					log.Warnf("Received %s, waiting for the linters to be killed", sig)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() { close(done) })
+		cancel()
+	}
 }
 
 // initConfig reads in config file and ENV variables if set.
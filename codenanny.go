@@ -18,19 +18,344 @@
 // OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
 // THE SOFTWARE.
 
-package main
+//Package codenanny runs multiple Go linters and code checkers on a repo and reports their findings.
+//It is the library behind the codenanny command, programs embed it by calling Run.
+package codenanny
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"time"
 
+	"github.com/lagarciag/codenanny/analyzers"
+	"github.com/lagarciag/codenanny/baseline"
+	"github.com/lagarciag/codenanny/build"
+	"github.com/lagarciag/codenanny/cache"
+	"github.com/lagarciag/codenanny/config"
+	"github.com/lagarciag/codenanny/coverage"
+	"github.com/lagarciag/codenanny/dirlister"
+	"github.com/lagarciag/codenanny/gotest"
+	"github.com/lagarciag/codenanny/installer"
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/linters"
+	"github.com/lagarciag/codenanny/loader"
+	"github.com/lagarciag/codenanny/parser"
+	"github.com/lagarciag/codenanny/suppress"
 	log "github.com/sirupsen/logrus"
-	"github.com/lagarciag/codenanny/cmd"
 )
 
-func main() {
-	log.SetOutput(os.Stderr)
-	formatter := log.TextFormatter{}
-	formatter.ForceColors = true
-	log.SetFormatter(&formatter)
-	cmd.Execute()
+//Options tell Run what to lint and how
+type Options struct {
+	//Root is the root of the repo to lint, empty uses the git root of the working dir
+	Root string
+	//Files are the go files to lint, relative paths are taken from Root
+	Files []string
+	//Dir is searched for go files to lint when Files is empty, relative paths are taken from Root
+	Dir string
+	//Config replaces the .codenanny file found in Root when set
+	Config *config.CodeNannyConfig
+	//Jobs is the number of linters run at the same time, zero means one per CPU
+	Jobs int
+	//Timeout overrides the timeout of the configuration when set
+	Timeout time.Duration
+	//NoInstall reports missing linters as tool errors instead of go getting them
+	NoInstall bool
+	//NoCache lints everything again instead of replaying the issues of unchanged code
+	NoCache bool
+	//CacheDir is where the issues are cached, empty uses the codenanny dir of the user cache dir
+	CacheDir string
+	//FailOn is the lowest severity that fails the run, empty means lint.SeverityError
+	FailOn lint.Severity
+	//Fix runs the fixers on the files before linting them, Report.Fixed lists the files they changed.
+	//Otherwise no linter writes to the files.
+	Fix bool
+	//Test runs the test linters, go test, even when .codenanny does not enable them
+	Test bool
+	//Base is the git revision the coverage of the changed lines is computed against, HEAD when empty
+	Base string
+	//Baseline is the baseline file, relative to Root: the issues it lists are left out of the report.
+	//A missing file is no baseline.
+	Baseline string
+	//Diff runs the fixers on a copy of the files instead, Report.Diff tells what they would change. It has precedence over Fix.
+	Diff bool
+	//Output receives the findings as text when set
+	Output io.Writer
+}
+
+//ConfigError is returned by Run when the configuration or the options are invalid
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+//Run lints the files selected by opts and returns the report of the run.
+//Findings and linter failures are part of the report, err is only set when
+//the run could not be set up, or together with a partial report when ctx is done.
+//Invalid configurations and options are reported as a *ConfigError.
+func Run(ctx context.Context, opts Options) (report *Report, err error) {
+	start := time.Now()
+	failOn := opts.FailOn
+	if failOn == "" {
+		failOn = lint.SeverityError
+	} else if _, err = lint.ParseSeverity(string(failOn)); err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+	runner, files, err := prepare(opts)
+	if err != nil {
+		return nil, err
+	}
+	known, err := loadBaseline(runner.Root, opts.Baseline)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return newReport(runner, nil, true, failOn, time.Since(start)), ctx.Err()
+	}
+	dirList := parser.Dirs(files)
+
+	var fixed []string
+	var fixResults []lint.Result
+	var patch string
+	switch {
+	case opts.Diff:
+		_, patch, fixResults, err = previewFixes(ctx, runner, files, dirList)
+	case opts.Fix:
+		fixed, fixResults, err = fixFiles(ctx, runner, files, dirList)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return newReport(runner, fixResults, true, failOn, time.Since(start)), ctx.Err()
+		}
+		return nil, err
+	}
+
+	//The packages are loaded once, the analyzers share them and the external linters get their paths
+	program, err := loader.Load(ctx, runner.Root, loader.DirPatterns(dirList))
+	if err != nil {
+		if ctx.Err() != nil {
+			return newReport(runner, nil, true, failOn, time.Since(start)), ctx.Err()
+		}
+		return nil, fmt.Errorf("could not find the packages to lint:%s", err.Error())
+	}
+	pkag := program.PkgPaths()
+	log.Debug("Packages:", pkag)
+	log.Debug("dirList:", dirList)
+
+	//The linters that need type information are kept off the code that does not compile
+	buildResults, broken := build.Check(program)
+	tasks, skipped := broken.Skip(runner.AllTasks(files, dirList, pkag))
+	compiled := broken.Compiled(program)
+	roots := compiled.Roots()
+	analysisLinters := runner.EnabledLinters(linters.ScopeAnalysis)
+	var cached []lint.Result
+	var keys []map[string]cache.Key
+	rc := openCache(opts, runner, program)
+	if rc != nil {
+		var cachedAnalysis []lint.Result
+		tasks, keys, cached = rc.lookup(tasks)
+		roots, cachedAnalysis = rc.lookupAnalysis(analysisLinters, roots)
+		cached = append(cached, cachedAnalysis...)
+	}
+
+	//The analyzers, the tests and the coverage gate run while the external linters do.
+	//go test caches the test results itself, they are not cached here.
+	//When both run, the coverage gate reads the profile of the tests instead of running them again.
+	profile, err := coverage.SharedProfile(runner)
+	if err != nil {
+		log.Warn("The coverage gate runs the tests on its own:", err)
+	}
+	if profile != "" {
+		defer os.Remove(profile)
+	}
+	var analysisResults, testResults, coverageResults []lint.Result
+	runAnalyzers := func(ctx context.Context) { analysisResults = analyzers.Run(ctx, runner, roots) }
+	runTests := func(ctx context.Context) { testResults = gotest.Run(ctx, runner, compiled, profile) }
+	runCoverage := func(ctx context.Context) {
+		coverageResults = coverage.Run(ctx, runner, compiled, opts.Base, profile)
+	}
+	//Each stage takes one of the --jobs workers. When the tests write the profile of the coverage gate, they run in one stage
+	stages := []lint.Stage{{Name: "analyzers", Run: runAnalyzers}}
+	if profile != "" {
+		stages = append(stages, lint.Stage{Name: gotest.Name, Run: func(ctx context.Context) {
+			runTests(ctx)
+			runCoverage(ctx)
+		}})
+	} else {
+		stages = append(stages, lint.Stage{Name: gotest.Name, Run: runTests}, lint.Stage{Name: coverage.Name, Run: runCoverage})
+	}
+
+	log.Debugf("Running %d linter tasks and %d stages on %d workers", len(tasks), len(stages), runner.Jobs)
+	results := runner.Execute(ctx, tasks, stages...)
+	if rc != nil && ctx.Err() == nil {
+		rc.store(results, keys)
+		rc.storeAnalysis(analysisResults, roots)
+		rc.record()
+	}
+	results = append(append(append(append(results, buildResults...), skipped...), analysisResults...), testResults...)
+	results = append(append(append(results, coverageResults...), cached...), fixResults...)
+	//The directives in the code apply to every issue, cached ones included
+	results, suppressed := suppress.Apply(runner.Root, runner.Config, files, results, ctx.Err() != nil)
+
+	report = newReport(runner, results, ctx.Err() != nil, failOn, time.Since(start))
+	report.Suppressed = suppressed
+	if known != nil {
+		report.Issues, report.Baselined = known.Filter(runner.Root, report.Issues)
+		report.judge(ctx.Err() != nil, failOn)
+	}
+	report.Fixed = fixed
+	report.Diff = patch
+	if opts.Output != nil {
+		if err = report.WriteText(opts.Output); err != nil {
+			return report, err
+		}
+	}
+	return report, ctx.Err()
+}
+
+//prepare returns the runner configured by opts and the files to lint, installing the missing linters
+func prepare(opts Options) (runner *lint.Runner, files []string, err error) {
+	if runner, err = newRunner(opts); err != nil {
+		return nil, nil, &ConfigError{Err: err}
+	}
+	if files, err = targetFiles(runner, opts); err != nil {
+		return nil, nil, &ConfigError{Err: err}
+	}
+
+	if opts.NoInstall {
+		runner.Disabled = installer.MissingLinters(runner.Registry)
+	} else if runner.Disabled, err = installer.CheckExternalDependencies(runner.Registry, runner.IsEnabled); err != nil {
+		return nil, nil, err
+	}
+	return runner, files, nil
+}
+
+//openCache returns the result cache selected by opts, nil when caching is off or the cache can't be opened
+func openCache(opts Options, runner *lint.Runner, program *loader.Program) *resultCache {
+	if opts.NoCache {
+		return nil
+	}
+	dir := opts.CacheDir
+	if dir == "" {
+		var err error
+		if dir, err = cache.DefaultDir(); err != nil {
+			log.Warn("Not caching, no user cache dir:", err)
+			return nil
+		}
+	}
+	c, err := cache.Open(dir)
+	if err != nil {
+		log.Warn("Not caching:", err)
+		return nil
+	}
+	return newResultCache(c, runner, program)
+}
+
+//newRunner returns the runner configured by opts
+func newRunner(opts Options) (runner *lint.Runner, err error) {
+	rootPath := opts.Root
+	if rootPath == "" {
+		if rootPath, err = config.GitRoot("."); err != nil {
+			return nil, err
+		}
+	}
+	var conf config.CodeNannyConfig
+	if opts.Config != nil {
+		conf = *opts.Config
+		conf.ResolveAliases()
+		if err = conf.Check(); err != nil {
+			return nil, err
+		}
+	} else if conf, err = config.Load(rootPath); err != nil {
+		return nil, err
+	}
+
+	if runner, err = lint.NewRunner(rootPath, conf); err != nil {
+		return nil, err
+	}
+	if opts.Jobs > 0 {
+		runner.Jobs = opts.Jobs
+	}
+	if opts.Timeout > 0 {
+		runner.Timeout = opts.Timeout
+	}
+	if opts.Test {
+		enabled := map[string]bool{}
+		for name, value := range runner.Config.Enabled {
+			enabled[name] = value
+		}
+		for _, linter := range runner.Registry.ByScope(linters.ScopeTest) {
+			enabled[linter.Name] = true
+		}
+		runner.Config.Enabled = enabled
+	}
+	return runner, nil
+}
+
+//loadBaseline reads the baseline file at path, relative to root. There is no baseline when path is empty or
+//the file does not exist, an unreadable one is a *ConfigError.
+func loadBaseline(root, path string) (known *baseline.Baseline, err error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	if known, err = baseline.Load(path); err != nil {
+		if os.IsNotExist(err) {
+			log.Debug("No baseline:", path)
+			return nil, nil
+		}
+		return nil, &ConfigError{Err: err}
+	}
+	log.Debugf("Baseline %s lists %d issues", path, known.Len())
+	return known, nil
+}
+
+//targetFiles returns the files to lint relative to the runner root, without the ignored paths
+func targetFiles(runner *lint.Runner, opts Options) (files []string, err error) {
+	files = opts.Files
+	if len(files) == 0 {
+		dir := opts.Dir
+		if dir == "" {
+			dir = "."
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(runner.Root, dir)
+		}
+		if files, _, err = dirlister.ListDir(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	var ignore *regexp.Regexp
+	if runner.Config.IgnorePath != "" {
+		if ignore, err = regexp.Compile(runner.Config.IgnorePath); err != nil {
+			return nil, fmt.Errorf("invalid ignore_path:%s", err.Error())
+		}
+	}
+	relFiles := make([]string, 0, len(files))
+	for _, file := range files {
+		if filepath.IsAbs(file) {
+			if file, err = filepath.Rel(runner.Root, file); err != nil {
+				return nil, err
+			}
+		}
+		if ignore != nil && ignore.MatchString(file) {
+			log.Debug("Ignore path matched:", file)
+			continue
+		}
+		relFiles = append(relFiles, file)
+	}
+	return relFiles, nil
 }
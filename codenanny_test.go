@@ -0,0 +1,480 @@
+package codenanny_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/lagarciag/codenanny"
+	"github.com/lagarciag/codenanny/baseline"
+	"github.com/lagarciag/codenanny/build"
+	"github.com/lagarciag/codenanny/config"
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/linters"
+)
+
+func TestMain(t *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	formatter := &log.TextFormatter{}
+	formatter.ForceColors = true
+	formatter.DisableTimestamp = true
+	log.SetFormatter(formatter)
+	v := t.Run()
+	os.Exit(v)
+
+}
+
+//newModule writes a go module with the passed files in a temporary dir
+func newModule(t *testing.T, files map[string]string) (root string) {
+	root = t.TempDir()
+	files["go.mod"] = "module example.com/nanny\n\ngo 1.16\n"
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+//onlyLinters returns a configuration disabling every builtin linter but the passed ones
+func onlyLinters(names ...string) *config.CodeNannyConfig {
+	conf := &config.CodeNannyConfig{Disabled: make(map[string]bool)}
+	for _, linter := range linters.All() {
+		conf.Disabled[linter.Name] = true
+	}
+	for _, name := range names {
+		delete(conf.Disabled, name)
+	}
+	return conf
+}
+
+func TestRun(t *testing.T) {
+	root := newModule(t, map[string]string{
+		"good.go": "package nanny\n\nfunc Good() {}\n",
+		"bad.go":  "package nanny\nfunc  Bad( ) {}\n",
+	})
+	out := &bytes.Buffer{}
+	report, err := codenanny.Run(context.Background(), codenanny.Options{
+		Root:      root,
+		Config:    onlyLinters("gofmt"),
+		NoInstall: true,
+		NoCache:   true,
+		Output:    out,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Verdict != codenanny.VerdictIssues || report.Passed() {
+		t.Error("Unexpected verdict:", report.Verdict)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Path != "bad.go" {
+		t.Error("gofmt should report bad.go only:", report.Issues)
+	}
+	if len(report.Linters) != 1 || report.Linters[0].Name != "gofmt" ||
+		report.Linters[0].Status != codenanny.StatusIssues || report.Linters[0].Tasks != 1 {
+		t.Error("Unexpected linter report:", report.Linters)
+	}
+	if !strings.HasPrefix(out.String(), "bad.go") {
+		t.Error("Issues must be written to Output:", out.String())
+	}
+
+	report, err = codenanny.Run(context.Background(), codenanny.Options{
+		Root:      root,
+		Files:     []string{filepath.Join(root, "good.go")},
+		Config:    onlyLinters("gofmt"),
+		NoInstall: true,
+		NoCache:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Passed() || len(report.Issues) != 0 {
+		t.Error("Clean files must pass:", report.Verdict, report.Issues)
+	}
+}
+
+func TestRunLinterStatus(t *testing.T) {
+	root := newModule(t, map[string]string{"good.go": "package nanny\n\nfunc Good() {}\n"})
+	conf := onlyLinters("gofmt")
+	conf.CustomLinters = map[string]config.CustomLinter{
+		"hung":    {Command: "sh", Args: []string{"-c", "sleep 10"}, Scope: "dir", Timeout: "100ms"},
+		"missing": {Command: "codenanny-no-such-linter", Scope: "dir"},
+		"broken":  {Command: "sh", Args: []string{"-c", "echo cannot load package; exit 1"}, Scope: "dir"},
+		"panicky": {Command: "sh", Args: []string{"-c", "echo 'panic: boom' >&2; exit 2"}, Scope: "dir"},
+	}
+	report, err := codenanny.Run(context.Background(), codenanny.Options{Root: root, Config: conf, NoInstall: true, NoCache: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Verdict != codenanny.VerdictError {
+		t.Error("A timed out linter must fail the run:", report.Verdict)
+	}
+	status := make(map[string]codenanny.Status)
+	for _, linter := range report.Linters {
+		status[linter.Name] = linter.Status
+	}
+	if status["gofmt"] != codenanny.StatusPassed || status["hung"] != codenanny.StatusTimeout ||
+		status["missing"] != codenanny.StatusToolError || status["broken"] != codenanny.StatusToolError ||
+		status["panicky"] != codenanny.StatusToolError {
+		t.Error("Unexpected linter status:", status)
+	}
+	if len(report.Issues) != 0 {
+		t.Error("The output of a broken linter must not be taken for issues:", report.Issues)
+	}
+	output := make(map[string]string)
+	for _, toolErr := range report.ToolErrors() {
+		output[toolErr.Linter] = toolErr.Output
+	}
+	if output["broken"] != "cannot load package\n" || output["panicky"] != "panic: boom\n" {
+		t.Error("Tool errors must carry the raw output of the linter:", output)
+	}
+}
+
+func TestRunCache(t *testing.T) {
+	root := newModule(t, map[string]string{
+		"good.go":  "package nanny\n\nfunc Good() {}\n",
+		"bad.go":   "package nanny\nfunc  Bad( ) {}\n",
+		"print.go": "package nanny\n\nimport \"fmt\"\n\nfunc Print() {\n\tfmt.Printf(\"%d\\n\", \"one\")\n}\n",
+	})
+	opts := codenanny.Options{
+		Root:      root,
+		Config:    onlyLinters("gofmt", "printf"),
+		NoInstall: true,
+		CacheDir:  t.TempDir(),
+	}
+	cached := func(report *codenanny.Report) (count map[string]int) {
+		count = make(map[string]int)
+		for _, linter := range report.Linters {
+			count[linter.Name] = linter.Cached
+		}
+		return count
+	}
+
+	first, err := codenanny.Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Issues) != 2 {
+		t.Fatal("Expected a gofmt and a printf issue:", first.Issues)
+	}
+	if count := cached(first); count["gofmt"] != 0 || count["printf"] != 0 {
+		t.Error("Nothing can be cached on the first run:", count)
+	}
+
+	second, err := codenanny.Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(first.Issues, second.Issues) {
+		t.Error("Cached issues must be replayed:", second.Issues)
+	}
+	if count := cached(second); count["gofmt"] != 1 || count["printf"] != 1 {
+		t.Error("Unchanged code must be replayed from the cache:", count)
+	}
+
+	if err = os.WriteFile(filepath.Join(root, "bad.go"), []byte("package nanny\n\nfunc Bad() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	third, err := codenanny.Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(third.Issues) != 1 || third.Issues[0].Linter != "printf" {
+		t.Error("Fixed code must not be replayed:", third.Issues)
+	}
+	for _, linter := range third.Linters {
+		if linter.Name == "gofmt" && (linter.Tasks != 2 || linter.Cached != 1) {
+			t.Error("Only the changed file must be linted again:", linter)
+		}
+		if linter.Name == "printf" && linter.Cached != 0 {
+			t.Error("A changed package must be analyzed again:", linter)
+		}
+	}
+}
+
+func TestRunCacheExternalTests(t *testing.T) {
+	root := newModule(t, map[string]string{
+		"nanny.go":      "package nanny\n\nfunc Good() {}\n",
+		"nanny_test.go": "package nanny_test\n\nimport \"testing\"\n\nfunc TestGood(t *testing.T) {}\n",
+	})
+	conf := onlyLinters("echo")
+	conf.CustomLinters = map[string]config.CustomLinter{
+		"echo": {Command: "echo", Scope: "package", Pattern: `^(?P<path>\S+\.go)$`},
+	}
+	opts := codenanny.Options{Root: root, Config: conf, NoInstall: true, CacheDir: t.TempDir()}
+	cached := func() int {
+		report, err := codenanny.Run(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, linter := range report.Linters {
+			if linter.Name == "echo" {
+				return linter.Cached
+			}
+		}
+		t.Fatal("echo did not run:", report.Linters)
+		return 0
+	}
+
+	if cached() != 0 || cached() != 1 {
+		t.Fatal("The package must be replayed once linted")
+	}
+	if err := os.WriteFile(filepath.Join(root, "nanny_test.go"), []byte("package nanny_test\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if cached() != 0 {
+		t.Error("A package whose external tests changed must be linted again")
+	}
+
+	conf.CustomLinters["echo"] = config.CustomLinter{Command: "echo", Scope: "package", Pattern: `^(?P<path>\S+\.go)$`,
+		Defaults: map[string]string{"unused": "1"}}
+	if cached() != 0 {
+		t.Error("A linter whose defaults changed must run again")
+	}
+}
+
+func TestRunSeverity(t *testing.T) {
+	root := newModule(t, map[string]string{"bad.go": "package nanny\nfunc  Bad( ) {}\n"})
+	conf := onlyLinters("gofmt")
+	conf.Severity = map[string]config.LinterSeverity{"gofmt": {Level: "warning"}}
+	opts := codenanny.Options{Root: root, Config: conf, NoInstall: true, NoCache: true}
+
+	report, err := codenanny.Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Passed() || report.Counts[lint.SeverityWarning] != 1 || report.Issues[0].Severity != lint.SeverityWarning {
+		t.Error("Warnings must be reported without failing the run:", report.Verdict, report.Counts)
+	}
+
+	opts.FailOn = lint.SeverityWarning
+	if report, err = codenanny.Run(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if report.Verdict != codenanny.VerdictIssues {
+		t.Error("Warnings must fail the run when FailOn is warning:", report.Verdict)
+	}
+
+	opts.FailOn = "fatal"
+	if _, err = codenanny.Run(context.Background(), opts); err == nil {
+		t.Error("Unknown FailOn severity must be rejected")
+	}
+}
+
+func TestRunBadConfig(t *testing.T) {
+	root := newModule(t, map[string]string{"good.go": "package nanny\n"})
+	conf := &config.CodeNannyConfig{Disabled: map[string]bool{"nosuchlinter": true}}
+	_, err := codenanny.Run(context.Background(), codenanny.Options{Root: root, Config: conf})
+	var confErr *codenanny.ConfigError
+	if !errors.As(err, &confErr) {
+		t.Error("An invalid configuration must be reported as a configuration error, got", err)
+	}
+	_, err = codenanny.Run(context.Background(), codenanny.Options{Root: root, NoCache: true, FailOn: "fatal"})
+	if !errors.As(err, &confErr) {
+		t.Error("An invalid fail on severity must be reported as a configuration error, got", err)
+	}
+}
+
+func TestFix(t *testing.T) {
+	good := "package nanny\n\nfunc Good() {}\n"
+	bad := "package nanny\nfunc  Bad( ) {\n\tx := 1\n\tx = x\n\t_ = x\n}\n"
+	root := newModule(t, map[string]string{"good.go": good, "bad.go": bad})
+	opts := codenanny.Options{Root: root, Config: onlyLinters("gofmt", "assign"), NoInstall: true, NoCache: true}
+
+	if _, err := codenanny.Run(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "bad.go")); string(content) != bad {
+		t.Error("Linting must not change the files:", string(content))
+	}
+
+	out := &bytes.Buffer{}
+	opts.Output = out
+	report, err := codenanny.Fix(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Verdict != codenanny.VerdictPass || !reflect.DeepEqual(report.Changed, []string{"bad.go"}) {
+		t.Error("Only bad.go must be fixed:", report.Verdict, report.Changed)
+	}
+	if out.String() != "bad.go\n" {
+		t.Error("The changed files must be printed:", out.String())
+	}
+	content, _ := os.ReadFile(filepath.Join(root, "bad.go"))
+	if strings.Contains(string(content), "x = x") || strings.Contains(string(content), "func  Bad") {
+		t.Error("gofmt and the assign suggested fix must be applied:", string(content))
+	}
+
+	opts.Fix = true
+	opts.Output = nil
+	if err = os.WriteFile(filepath.Join(root, "good.go"), []byte("package nanny\nfunc  Good() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lintReport, err := codenanny.Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lintReport.Passed() || !reflect.DeepEqual(lintReport.Fixed, []string{"good.go"}) {
+		t.Error("Run with Fix must lint the fixed files:", lintReport.Verdict, lintReport.Issues, lintReport.Fixed)
+	}
+}
+
+func TestFixSuppressed(t *testing.T) {
+	bad := "package nanny\n\nfunc Bad() {\n\tx := 1\n\tx = x //nanny:ignore assign -- on purpose\n\t_ = x\n}\n"
+	root := newModule(t, map[string]string{"bad.go": bad})
+	opts := codenanny.Options{Root: root, Config: onlyLinters("assign"), NoInstall: true, NoCache: true}
+
+	report, err := codenanny.Fix(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Changed) != 0 {
+		t.Error("Nothing must be fixed:", report.Changed)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "bad.go")); string(content) != bad {
+		t.Error("The fix of an ignored issue must not be applied:", string(content))
+	}
+}
+
+func TestFixDiff(t *testing.T) {
+	bad := "package nanny\nfunc  Bad( ) {}\n"
+	root := newModule(t, map[string]string{"bad.go": bad, "good.go": "package nanny\n\nfunc Good() {}\n"})
+	opts := codenanny.Options{Root: root, Config: onlyLinters("gofmt"), NoInstall: true, NoCache: true, Diff: true}
+
+	report, err := codenanny.Fix(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "diff --git a/bad.go b/bad.go\n--- a/bad.go\n+++ b/bad.go\n" +
+		"@@ -1,2 +1,3 @@\n package nanny\n-func  Bad( ) {}\n+\n+func Bad() {}\n"
+	if report.Diff != expected || !reflect.DeepEqual(report.Changed, []string{"bad.go"}) {
+		t.Errorf("Unexpected diff of %v:\n%s", report.Changed, report.Diff)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "bad.go")); string(content) != bad {
+		t.Error("A diff must not change the files:", string(content))
+	}
+
+	opts.Fix = true
+	lintReport, err := codenanny.Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lintReport.Diff != expected || len(lintReport.Fixed) != 0 || len(lintReport.Issues) != 1 {
+		t.Errorf("Run with Diff must lint the files as they are and carry the diff:%v %v\n%s",
+			lintReport.Issues, lintReport.Fixed, lintReport.Diff)
+	}
+}
+
+func TestFixDiffEmbed(t *testing.T) {
+	//The package only compiles with the file it embeds, the diff is computed in place
+	bad := "package nanny\n\nimport _ \"embed\"\n\n//go:embed data.txt\nvar data string\n\n" +
+		"func Bad() {\n\tx := data\n\tx = x\n\t_ = x\n}\n"
+	root := newModule(t, map[string]string{"bad.go": bad, "data.txt": "data\n"})
+	opts := codenanny.Options{Root: root, Config: onlyLinters("gofmt", "assign"), NoInstall: true, NoCache: true, Diff: true}
+
+	report, err := codenanny.Fix(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "diff --git a/bad.go b/bad.go\n--- a/bad.go\n+++ b/bad.go\n" +
+		"@@ -7,6 +7,5 @@\n \n func Bad() {\n \tx := data\n-\tx = x\n \t_ = x\n }\n"
+	if report.Diff != expected || !reflect.DeepEqual(report.Changed, []string{"bad.go"}) {
+		t.Errorf("Unexpected diff of %v:\n%s", report.Changed, report.Diff)
+	}
+	if content, _ := os.ReadFile(filepath.Join(root, "bad.go")); string(content) != bad {
+		t.Error("A diff must not change the files:", string(content))
+	}
+}
+
+func TestRunBroken(t *testing.T) {
+	root := newModule(t, map[string]string{
+		"broken.go": "package nanny\n\nvar x int = \"one\"\n",
+	})
+	conf := onlyLinters("vet")
+	conf.Enabled = map[string]bool{"vet": true}
+	report, err := codenanny.Run(context.Background(), codenanny.Options{
+		Root:      root,
+		Config:    conf,
+		NoInstall: true,
+		NoCache:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Verdict != codenanny.VerdictIssues {
+		t.Error("Unexpected verdict:", report.Verdict)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Linter != build.Name || report.Issues[0].Path != "broken.go" {
+		t.Error("The compile error must be reported by the build stage only:", report.Issues)
+	}
+	if len(report.Linters) != 2 || report.Linters[1].Name != "vet" || report.Linters[1].Status != codenanny.StatusSkipped {
+		t.Error("vet needs types, it must be skipped:", report.Linters)
+	}
+
+	//Broken code never passes, even when its compile errors are silenced
+	silenced := "//nanny:file-ignore build -- known\npackage nanny\n\nvar x int = \"one\"\n"
+	if err = os.WriteFile(filepath.Join(root, "broken.go"), []byte(silenced), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report, err = codenanny.Run(context.Background(), codenanny.Options{
+		Root:      root,
+		Config:    conf,
+		NoInstall: true,
+		NoCache:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 0 || report.Verdict != codenanny.VerdictError {
+		t.Error("A run that skipped broken code must not pass:", report.Verdict, report.Issues)
+	}
+}
+
+func TestRunBaseline(t *testing.T) {
+	root := newModule(t, map[string]string{
+		"bad.go": "package nanny\nfunc  Bad( ) {}\n",
+	})
+	opts := codenanny.Options{
+		Root:      root,
+		Config:    onlyLinters("gofmt"),
+		NoInstall: true,
+		NoCache:   true,
+		Baseline:  baseline.DefaultFile,
+	}
+	report, err := codenanny.Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Verdict != codenanny.VerdictIssues || report.Baselined != 0 {
+		t.Fatal("A missing baseline must leave every issue:", report.Verdict, report.Baselined)
+	}
+	if err = baseline.New(root, report.Issues).Save(filepath.Join(root, baseline.DefaultFile)); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = codenanny.Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Passed() || report.Baselined != 1 || len(report.Issues) != 0 {
+		t.Error("The issues of the baseline must not fail the run:", report.Verdict, report.Issues)
+	}
+
+	if err = os.WriteFile(filepath.Join(root, "worse.go"), []byte("package nanny\nfunc  Worse( ) {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report, err = codenanny.Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Verdict != codenanny.VerdictIssues || len(report.Issues) != 1 || report.Issues[0].Path != "worse.go" {
+		t.Error("New issues must fail the run:", report.Verdict, report.Issues)
+	}
+}
@@ -19,12 +19,16 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/lagarciag/codenanny/linters"
 	"gopkg.in/yaml.v2"
 
 	"strconv"
@@ -33,84 +37,235 @@ import (
 //Version holds the current version
 var Version = "0.0.2"
 
-//GlobalConfig holds configuration functionality for Codennay
-var GlobalConfig CodeNannyConfig
-
 //CodeNannyConfig is the struct used to marshall in the configuration
 type CodeNannyConfig struct {
-	Version       string              `yaml:"required_version"`
-	Disabled      map[string]bool     `yaml:"disabled"`
-	IgnorePattern map[string][]string `yaml:"ignore_pattern"`
-	IgnorePath    string              `yaml:"ignore_path_pattern"`
+	Version             string                       `yaml:"required_version"`
+	Disabled            map[string]bool              `yaml:"disabled"`
+	Enabled             map[string]bool              `yaml:"enabled"`
+	IgnorePattern       map[string][]IgnorePattern   `yaml:"ignore_pattern"`
+	IgnorePath          string                       `yaml:"ignore_path_pattern"`
+	Settings            map[string]map[string]string `yaml:"settings"`
+	CustomLinters       map[string]CustomLinter      `yaml:"custom_linters"`
+	Timeout             string                       `yaml:"timeout"`
+	Precedence          []string                     `yaml:"precedence"`
+	Severity            map[string]LinterSeverity    `yaml:"severity"`
+	Coverage            Coverage                     `yaml:"coverage"`
+	Directives          Directives                   `yaml:"directives"`
+	ExpiredSuppressions string                       `yaml:"expired_suppressions"`
 }
 
-//LoadConfig loads and processes the configuration file
-func LoadConfig() (err error) {
-	var yamlFile []byte
+//aliases maps the deprecated linter names to the linters that replaced them
+var aliases = map[string]string{
+	//testify assertions are reported by the test linter
+	"testify": "test",
+}
 
-	//Find out what the Root Path is
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	tmpRootPath, err := cmd.Output()
-	if err != nil {
-		log.Errorf("Is this a git repo???:%s", err.Error())
-		return err
+//LinterName returns the linter that replaced name when name is deprecated, name otherwise
+func LinterName(name string) string {
+	if replacement, found := aliases[name]; found {
+		log.Warnf("The %s linter is deprecated, it is an alias of %s", name, replacement)
+		return replacement
 	}
+	return name
+}
 
-	//Trim return character
-	rootPath := strings.TrimSpace(string(tmpRootPath))
-	err = os.Chdir(rootPath)
-	if err != nil {
-		return err
+//ResolveAliases renames the deprecated linters named in the configuration to the ones that replaced them.
+//The maps and the precedence are replaced, not changed, they may be shared with another configuration.
+func (c *CodeNannyConfig) ResolveAliases() {
+	c.Disabled = renameKeys(c.Disabled)
+	c.Enabled = renameKeys(c.Enabled)
+	c.IgnorePattern = renameKeys(c.IgnorePattern)
+	c.Settings = renameKeys(c.Settings)
+	c.Severity = renameKeys(c.Severity)
+	if c.Precedence != nil {
+		precedence := make([]string, len(c.Precedence))
+		for id, name := range c.Precedence {
+			precedence[id] = LinterName(name)
+		}
+		c.Precedence = precedence
 	}
+}
 
-	configFile := rootPath + "/.codenanny"
-	if _, err := os.Stat(configFile); err == nil {
-		log.Debug("Found Config file")
-		yamlFile, err = ioutil.ReadFile(configFile)
-		if err != nil {
-			panic("Could not load file")
+//renameKeys returns a copy of m with the deprecated linters renamed, the values set for the new name win
+func renameKeys[V any](m map[string]V) (renamed map[string]V) {
+	if m == nil {
+		return nil
+	}
+	renamed = make(map[string]V, len(m))
+	for key, value := range m {
+		if _, found := aliases[key]; !found {
+			renamed[key] = value
 		}
-		//Unmarshal yaml file into allocated go struct
-		if err := yaml.Unmarshal(yamlFile, &GlobalConfig); err != nil {
-			return err
+	}
+	for key, value := range m {
+		if _, found := aliases[key]; !found {
+			continue
 		}
-		//Check Version
-		if err := CheckVersion(); err != nil {
-			return err
+		newKey := LinterName(key)
+		if _, taken := renamed[newKey]; !taken {
+			renamed[newKey] = value
 		}
+	}
+	return renamed
+}
 
-		log.Debug(GlobalConfig)
-	} else {
+//GitRoot returns the root path of the git repo dir belongs to
+func GitRoot(dir string) (rootPath string, err error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+	tmpRootPath, err := cmd.Output()
+	if err != nil {
+		log.Errorf("Is this a git repo???:%s", err.Error())
+		return rootPath, err
+	}
+
+	//Trim return character
+	return strings.TrimSpace(string(tmpRootPath)), nil
+}
+
+//Load loads and processes the .codenanny file found in rootPath.
+//A missing file is not an error, the zero configuration is returned.
+func Load(rootPath string) (conf CodeNannyConfig, err error) {
+	configFile := filepath.Join(rootPath, ".codenanny")
+	if _, err = os.Stat(configFile); err != nil {
 		log.Debug("No config file cound in:", configFile)
+		return conf, nil
 	}
 
-	return nil
+	log.Debug("Found Config file")
+	yamlFile, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return conf, err
+	}
+	//Unmarshal yaml file into allocated go struct
+	if err = yaml.Unmarshal(yamlFile, &conf); err != nil {
+		return conf, err
+	}
+	conf.ResolveAliases()
+	if err = conf.Check(); err != nil {
+		return conf, err
+	}
+	log.Debug(conf)
+	return conf, nil
+}
+
+//Check validates the configuration
+func (c CodeNannyConfig) Check() (err error) {
+	//Check Version
+	if err = c.CheckVersion(); err != nil {
+		return err
+	}
+	//Check the linters declared in the file
+	registry, err := c.Registry()
+	if err != nil {
+		return err
+	}
+	//Check that every linter named in the file is known
+	if err = c.CheckLinterNames(registry); err != nil {
+		return err
+	}
+	//Check that the severity levels are known
+	if err = c.CheckSeverities(); err != nil {
+		return err
+	}
+	//Check the ignore patterns and their expiry dates
+	if err = c.CheckIgnorePatterns(); err != nil {
+		return err
+	}
+	//Check that the coverage thresholds are percentages
+	if err = c.CheckCoverage(); err != nil {
+		return err
+	}
+	//Check that the timeouts can be parsed
+	return c.CheckTimeouts()
 }
 
 func versionToInt(ver string) (verInt int, err error) {
 	slicedVersion := strings.Split(ver, ".")
+	if len(slicedVersion) != 3 {
+		return verInt, fmt.Errorf("version %s is not in major.minor.patch format", ver)
+	}
 	concatVersion := slicedVersion[0] + slicedVersion[1] + slicedVersion[2]
 	verInt, err = strconv.Atoi(concatVersion)
 	return verInt, err
 }
 
 //CheckVersion checks that .codenanny required version matches the actual version
-func CheckVersion() (err error) {
-	if GlobalConfig.Version != "" {
-		requiredVersion, errV := versionToInt(GlobalConfig.Version)
-		if err != nil {
-			return errV
+func (c CodeNannyConfig) CheckVersion() (err error) {
+	if c.Version != "" {
+		var requiredVersion, actualVersion int
+		if requiredVersion, err = versionToInt(c.Version); err != nil {
+			return err
 		}
-		actualVersion, errV := versionToInt(Version)
-		if err != nil {
-			return errV
+		if actualVersion, err = versionToInt(Version); err != nil {
+			return err
 		}
 		if requiredVersion > actualVersion {
-			//log.Errorf("The .codenanny file (configuration) says that codenanny version %s is required, but this is %s ",GlobalConfig.Version,Version)
-			log.Fatalf("The .codenanny file (configuration) says that codenanny version %s is required, but this is %s ", GlobalConfig.Version, Version)
+			return fmt.Errorf("The .codenanny file (configuration) says that codenanny version %s is required, but this is %s ", c.Version, Version)
+		}
+		log.Debug("Required version:", requiredVersion)
+		log.Debug("Actual version:", actualVersion)
+	}
+	return nil
+}
+
+//CheckLinterNames checks that the linters referred by .codenanny are in registry
+func (c CodeNannyConfig) CheckLinterNames(registry *linters.Registry) (err error) {
+	for name := range c.Disabled {
+		if _, found := registry.Get(name); !found {
+			return fmt.Errorf("the .codenanny file disables unknown linter %s", name)
 		}
-		log.Info("Required version:", requiredVersion)
-		log.Info("Actual version:", actualVersion)
 	}
-	return err
+	for name := range c.Enabled {
+		if _, found := registry.Get(name); !found {
+			return fmt.Errorf("the .codenanny file enables unknown linter %s", name)
+		}
+	}
+	for name := range c.Settings {
+		if _, found := registry.Get(name); !found {
+			return fmt.Errorf("the .codenanny file has settings for unknown linter %s", name)
+		}
+	}
+	for name := range c.IgnorePattern {
+		if _, found := registry.Get(name); !found {
+			return fmt.Errorf("the .codenanny file has ignore patterns for unknown linter %s", name)
+		}
+	}
+	for name := range c.Severity {
+		if _, found := registry.Get(name); !found {
+			return fmt.Errorf("the .codenanny file sets the severity of unknown linter %s", name)
+		}
+	}
+	for _, name := range c.Precedence {
+		if _, found := registry.Get(name); !found {
+			return fmt.Errorf("the .codenanny file gives precedence to unknown linter %s", name)
+		}
+	}
+	return nil
+}
+
+//CheckTimeouts checks the global and per linter timeouts of .codenanny
+func (c CodeNannyConfig) CheckTimeouts() (err error) {
+	if _, err = c.GlobalTimeout(); err != nil {
+		return err
+	}
+	for name, settings := range c.Settings {
+		if value, found := settings["timeout"]; found {
+			if _, err = time.ParseDuration(value); err != nil {
+				return fmt.Errorf("the .codenanny file has an invalid timeout for %s:%s", name, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+//GlobalTimeout returns the timeout applied to every linter, zero when not set
+func (c CodeNannyConfig) GlobalTimeout() (timeout time.Duration, err error) {
+	if c.Timeout == "" {
+		return 0, nil
+	}
+	if timeout, err = time.ParseDuration(c.Timeout); err != nil {
+		return 0, fmt.Errorf("the .codenanny file has an invalid timeout:%s", err.Error())
+	}
+	return timeout, nil
 }
@@ -21,9 +21,12 @@ package config_test
 import (
 	"os"
 	"testing"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/lagarciag/codenanny/config"
+	"github.com/lagarciag/codenanny/linters"
+	"gopkg.in/yaml.v2"
 )
 
 func TestMain(t *testing.M) {
@@ -36,7 +39,206 @@ func TestMain(t *testing.M) {
 
 func TestConfigBasic(t *testing.T) {
 	log.Debug("TestConfig run")
-	if err := config.LoadConfig(); err != nil {
+	rootPath, err := config.GitRoot(".")
+	if err != nil {
+		t.Fatal("not in a git repo:", err)
+	}
+	if _, err := config.Load(rootPath); err != nil {
 		t.Error("error loading config:", err)
 	}
 }
+
+func TestCustomLinters(t *testing.T) {
+	conf := config.CodeNannyConfig{
+		CustomLinters: map[string]config.CustomLinter{
+			"protocheck": {
+				Command: "protocheck",
+				Args:    []string{"-strict"},
+				Scope:   "package",
+				Pattern: `^(?P<path>[^:]+):(?P<line>\d+): (?P<message>.*)$`,
+				Install: "github.com/acme/protocheck",
+				Timeout: "30s",
+			},
+		},
+		Disabled: map[string]bool{"protocheck": false},
+	}
+	registry, err := conf.Registry()
+	if err != nil {
+		t.Fatal("Could not register custom linter:", err)
+	}
+	linter, found := registry.Get("protocheck")
+	if !found || linter.Scope != linters.ScopePackage || !linter.Enabled || linter.Timeout != 30*time.Second {
+		t.Error("Custom linter not registered as declared:", linter)
+	}
+	if _, found = linters.Get("protocheck"); found {
+		t.Error("Custom linters must not leak into the default registry")
+	}
+	if err = conf.Check(); err != nil {
+		t.Error("Configuration referring to a custom linter must be valid:", err)
+	}
+
+	conf.CustomLinters = map[string]config.CustomLinter{
+		"vet": {Command: "myvet", Scope: "packages"},
+	}
+	if _, err = conf.Registry(); err == nil {
+		t.Error("Custom linters must not replace builtin ones")
+	}
+
+	conf.CustomLinters = map[string]config.CustomLinter{
+		"sqlcheck": {Command: "sqlcheck", Scope: "everywhere"},
+	}
+	if _, err = conf.Registry(); err == nil {
+		t.Error("Custom linters with an unknown scope must be rejected")
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	var conf config.CodeNannyConfig
+	data := []byte("severity:\n  golint: warning\n  staticcheck:\n    default: error\n    SA1019: info\n")
+	if err := yaml.Unmarshal(data, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if level := conf.SeverityOf("golint", "any"); level != "warning" {
+		t.Error("Linter severity must apply to every rule, got", level)
+	}
+	if level := conf.SeverityOf("staticcheck", "SA1019"); level != "info" {
+		t.Error("Rule severity must override the linter one, got", level)
+	}
+	if level := conf.SeverityOf("staticcheck", "SA4006"); level != "error" {
+		t.Error("Default severity must apply to the other rules, got", level)
+	}
+	if level := conf.SeverityOf("vet", ""); level != "" {
+		t.Error("Linters without severity must keep theirs, got", level)
+	}
+
+	conf = config.CodeNannyConfig{Severity: map[string]config.LinterSeverity{"golint": {Level: "fatal"}}}
+	if err := conf.Check(); err == nil {
+		t.Error("Unknown severity levels must be rejected")
+	}
+	conf = config.CodeNannyConfig{Severity: map[string]config.LinterSeverity{"nosuchlinter": {Level: "info"}}}
+	if err := conf.Check(); err == nil {
+		t.Error("Severity of unknown linters must be rejected")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	conf := config.CodeNannyConfig{Disabled: map[string]bool{"nosuchlinter": true}}
+	if err := conf.Check(); err == nil {
+		t.Error("Unknown linters must be rejected")
+	}
+	conf = config.CodeNannyConfig{Timeout: "soon"}
+	if err := conf.Check(); err == nil {
+		t.Error("Invalid timeouts must be rejected")
+	}
+	conf = config.CodeNannyConfig{Precedence: []string{"staticcheck", "nosuchlinter"}}
+	if err := conf.Check(); err == nil {
+		t.Error("Unknown linters in precedence must be rejected")
+	}
+	conf = config.CodeNannyConfig{Version: "99.0.0"}
+	if err := conf.Check(); err == nil {
+		t.Error("Newer required versions must be rejected")
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	var conf config.CodeNannyConfig
+	if conf.Coverage.IsSet() {
+		t.Error("Coverage must not be set without thresholds")
+	}
+	data := []byte("coverage:\n  min: 70\n  changed: 80\n  paths:\n    lint:\n      min: 85\n    lint/fix:\n      changed: 90\n")
+	if err := yaml.Unmarshal(data, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if !conf.Coverage.IsSet() {
+		t.Error("Coverage must be set")
+	}
+	threshold := conf.Coverage.ThresholdOf("cmd")
+	if *threshold.Min != 70 || *threshold.Changed != 80 {
+		t.Error("Packages out of paths must get the global thresholds, got", *threshold.Min, *threshold.Changed)
+	}
+	threshold = conf.Coverage.ThresholdOf("lint/fix")
+	if *threshold.Min != 85 || *threshold.Changed != 90 {
+		t.Error("Each threshold must come from the longest path setting it, got", *threshold.Min, *threshold.Changed)
+	}
+	threshold = conf.Coverage.ThresholdOf("linters")
+	if *threshold.Min != 70 {
+		t.Error("Paths must match whole dirs, got", *threshold.Min)
+	}
+
+	if err := yaml.Unmarshal([]byte("coverage:\n  paths:\n    lint:\n      min: 120\n"), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.Check(); err == nil {
+		t.Error("Thresholds above 100 must be rejected")
+	}
+}
+
+func TestIgnorePattern(t *testing.T) {
+	var conf config.CodeNannyConfig
+	data := []byte("ignore_pattern:\n  golint:\n    - \"should have comment\"\n    - pattern: \"exported\"\n      until: 2026-12-31\n      owner: alice\n")
+	if err := yaml.Unmarshal(data, &conf); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.Check(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []config.IgnorePattern{{Pattern: "should have comment"}, {Pattern: "exported", Until: "2026-12-31", Owner: "alice"}}
+	if patterns := conf.IgnorePattern["golint"]; len(patterns) != 2 || patterns[0] != expected[0] || patterns[1] != expected[1] {
+		t.Error("Unexpected patterns:", patterns)
+	}
+	if conf.ExpiredSeverity() != "warning" {
+		t.Error("Expired suppressions must be warnings by default, got", conf.ExpiredSeverity())
+	}
+
+	until, err := config.ParseUntil("2026-12-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Expired(until, until.Add(23*time.Hour)) || !config.Expired(until, until.AddDate(0, 0, 1)) {
+		t.Error("Suppressions must expire once their last day is over")
+	}
+
+	conf.IgnorePattern["golint"][1].Until = "next year"
+	if err = conf.Check(); err == nil {
+		t.Error("Invalid expiry dates must be rejected")
+	}
+	conf.IgnorePattern["golint"][1].Until = ""
+	conf.ExpiredSuppressions = "fatal"
+	if err = conf.Check(); err == nil {
+		t.Error("Unknown severities of the expired suppressions must be rejected")
+	}
+}
+
+func TestAliases(t *testing.T) {
+	var conf config.CodeNannyConfig
+	data := []byte("enabled:\n  testify: true\nsettings:\n  testify:\n    timeout: 5m\n  test:\n    timeout: 1m\nprecedence: [vet, testify]\n")
+	if err := yaml.Unmarshal(data, &conf); err != nil {
+		t.Fatal(err)
+	}
+	enabled := conf.Enabled
+	conf.ResolveAliases()
+	if err := conf.Check(); err != nil {
+		t.Fatal("testify must still be accepted:", err)
+	}
+	if !conf.Enabled["test"] || len(conf.Enabled) != 1 {
+		t.Error("testify must enable test:", conf.Enabled)
+	}
+	if conf.Settings["test"]["timeout"] != "1m" || len(conf.Settings) != 1 {
+		t.Error("The settings of test must win over the ones of testify:", conf.Settings)
+	}
+	if len(conf.Precedence) != 2 || conf.Precedence[1] != "test" {
+		t.Error("testify must be renamed in the precedence:", conf.Precedence)
+	}
+	if !enabled["testify"] {
+		t.Error("The maps of the configuration must not be changed in place")
+	}
+	if config.LinterName("golint") != "golint" {
+		t.Error("Linters that are not deprecated must keep their name")
+	}
+}
@@ -0,0 +1,93 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+//CoverageThreshold is the minimum statement coverage, in percent, of a package and of its changed lines.
+//Unset thresholds are not enforced.
+type CoverageThreshold struct {
+	Min     *float64 `yaml:"min"`
+	Changed *float64 `yaml:"changed"`
+}
+
+//Coverage is the coverage section of .codenanny: the thresholds of every package,
+//and in Paths the thresholds of the packages below a directory
+type Coverage struct {
+	CoverageThreshold `yaml:",inline"`
+	Paths             map[string]CoverageThreshold `yaml:"paths"`
+}
+
+//IsSet tells if a coverage threshold is configured
+func (c Coverage) IsSet() bool {
+	if c.Min != nil || c.Changed != nil {
+		return true
+	}
+	for _, threshold := range c.Paths {
+		if threshold.Min != nil || threshold.Changed != nil {
+			return true
+		}
+	}
+	return false
+}
+
+//ThresholdOf returns the thresholds of the package in dir, relative to the repo root.
+//Each threshold comes from the longest path of Paths holding dir that sets it, or else from the global ones.
+func (c Coverage) ThresholdOf(dir string) (threshold CoverageThreshold) {
+	threshold = c.CoverageThreshold
+	minLength, changedLength := -1, -1
+	dir = filepath.ToSlash(filepath.Clean(dir))
+	for path, pathThreshold := range c.Paths {
+		path = strings.TrimSuffix(filepath.ToSlash(filepath.Clean(path)), "/")
+		if path != "." && dir != path && !strings.HasPrefix(dir, path+"/") {
+			continue
+		}
+		if pathThreshold.Min != nil && len(path) > minLength {
+			threshold.Min, minLength = pathThreshold.Min, len(path)
+		}
+		if pathThreshold.Changed != nil && len(path) > changedLength {
+			threshold.Changed, changedLength = pathThreshold.Changed, len(path)
+		}
+	}
+	return threshold
+}
+
+//CheckCoverage checks that the thresholds of the coverage section are percentages
+func (c CodeNannyConfig) CheckCoverage() (err error) {
+	check := func(where string, threshold CoverageThreshold) error {
+		for _, value := range []*float64{threshold.Min, threshold.Changed} {
+			if value != nil && (*value < 0 || *value > 100) {
+				return fmt.Errorf("the .codenanny file has a coverage threshold of %v%s, it must be between 0 and 100", *value, where)
+			}
+		}
+		return nil
+	}
+	if err = check("", c.Coverage.CoverageThreshold); err != nil {
+		return err
+	}
+	for path, threshold := range c.Coverage.Paths {
+		if err = check(" for "+path, threshold); err != nil {
+			return err
+		}
+	}
+	return nil
+}
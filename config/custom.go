@@ -0,0 +1,90 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lagarciag/codenanny/linters"
+	log "github.com/sirupsen/logrus"
+)
+
+//CustomLinter is a user defined linter declared in the custom_linters section of .codenanny
+type CustomLinter struct {
+	Command     string            `yaml:"command"`
+	Args        []string          `yaml:"args"`
+	FixArgs     []string          `yaml:"fix_args"`
+	PreviewArgs []string          `yaml:"preview_args"`
+	Scope       string            `yaml:"scope"`
+	Pattern     string            `yaml:"pattern"`
+	Install     string            `yaml:"install"`
+	Defaults    map[string]string `yaml:"defaults"`
+	Timeout     string            `yaml:"timeout"`
+	NeedsTypes  bool              `yaml:"needs_types"`
+}
+
+//Linter converts the declaration into a linter named name
+func (c CustomLinter) Linter(name string) (linter linters.Linter, err error) {
+	var timeout time.Duration
+	if c.Timeout != "" {
+		if timeout, err = time.ParseDuration(c.Timeout); err != nil {
+			return linter, fmt.Errorf("custom linter %s has an invalid timeout:%s", name, err.Error())
+		}
+	}
+	return linters.Linter{
+		Name:        name,
+		Command:     c.Command,
+		Args:        c.Args,
+		FixArgs:     c.FixArgs,
+		PreviewArgs: c.PreviewArgs,
+		Scope:       linters.Scope(c.Scope),
+		Pattern:     c.Pattern,
+		InstallPath: c.Install,
+		Enabled:     true,
+		Defaults:    c.Defaults,
+		Timeout:     timeout,
+		NeedsTypes:  c.NeedsTypes,
+	}, nil
+}
+
+//Registry returns a registry holding the builtin linters and the custom linters of the configuration
+func (c CodeNannyConfig) Registry() (registry *linters.Registry, err error) {
+	registry = linters.Builtin()
+	//Sort names so errors do not depend on map order
+	names := make([]string, 0, len(c.CustomLinters))
+	for name := range c.CustomLinters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var linter linters.Linter
+		if linter, err = c.CustomLinters[name].Linter(name); err != nil {
+			return nil, err
+		}
+		if _, found := registry.Get(name); found {
+			return nil, fmt.Errorf("custom linter %s clashes with a builtin linter", name)
+		}
+		if err = registry.Register(linter); err != nil {
+			return nil, fmt.Errorf("invalid custom linter in .codenanny:%s", err.Error())
+		}
+		log.Debug("Registered custom linter:", name)
+	}
+	return registry, nil
+}
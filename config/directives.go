@@ -0,0 +1,26 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package config
+
+//Directives is the directives section of .codenanny, the policy of the //nanny:ignore comments found in the code
+type Directives struct {
+	//RequireReason reports the directives without a reason after --, they suppress nothing
+	RequireReason bool `yaml:"require_reason"`
+	//ReportUnused reports the directives that no longer suppress any issue
+	ReportUnused bool `yaml:"report_unused"`
+}
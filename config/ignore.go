@@ -0,0 +1,90 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+//UntilLayout is the format of the expiry dates of the suppressions
+const UntilLayout = "2006-01-02"
+
+//IgnorePattern is an entry of the ignore_pattern section of .codenanny. In the file it is either a
+//regular expression or a mapping with the pattern, the last day it applies and who owns it.
+type IgnorePattern struct {
+	Pattern string `yaml:"pattern"`
+	Until   string `yaml:"until"`
+	Owner   string `yaml:"owner"`
+}
+
+//UnmarshalYAML accepts a regular expression or a mapping with pattern, until and owner
+func (p *IgnorePattern) UnmarshalYAML(unmarshal func(interface{}) error) (err error) {
+	if err = unmarshal(&p.Pattern); err == nil {
+		return nil
+	}
+	type plain IgnorePattern
+	if err = unmarshal((*plain)(p)); err != nil {
+		return fmt.Errorf("ignore patterns must be a regular expression or a mapping with pattern, until and owner")
+	}
+	return nil
+}
+
+//ParseUntil parses an expiry date, the suppression expires once the day is over
+func ParseUntil(value string) (until time.Time, err error) {
+	if until, err = time.ParseInLocation(UntilLayout, value, time.Local); err != nil {
+		return until, fmt.Errorf("invalid expiry date %q, expected %s", value, UntilLayout)
+	}
+	return until, nil
+}
+
+//Expired tells if a suppression valid until the day of until has expired at now
+func Expired(until, now time.Time) bool {
+	return !until.IsZero() && !now.Before(until.AddDate(0, 0, 1))
+}
+
+//ExpiredSeverity returns the severity of the expired suppressions, warning unless set in .codenanny
+func (c CodeNannyConfig) ExpiredSeverity() string {
+	if c.ExpiredSuppressions == "" {
+		return "warning"
+	}
+	return c.ExpiredSuppressions
+}
+
+//CheckIgnorePatterns checks the patterns and the expiry dates of the ignore_pattern section and the
+//severity of the expired suppressions
+func (c CodeNannyConfig) CheckIgnorePatterns() (err error) {
+	for name, patterns := range c.IgnorePattern {
+		for _, pattern := range patterns {
+			if _, err = regexp.Compile(pattern.Pattern); err != nil {
+				return fmt.Errorf("the .codenanny file has an invalid ignore pattern for %s:%s", name, err.Error())
+			}
+			if pattern.Until == "" {
+				continue
+			}
+			if _, err = ParseUntil(pattern.Until); err != nil {
+				return fmt.Errorf("the .codenanny file has an ignore pattern for %s with an %s", name, err.Error())
+			}
+		}
+	}
+	if c.ExpiredSuppressions != "" && !IsLevel(c.ExpiredSuppressions) {
+		return fmt.Errorf("the .codenanny file has an unknown severity %q for expired_suppressions, use one of %v", c.ExpiredSuppressions, Levels)
+	}
+	return nil
+}
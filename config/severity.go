@@ -0,0 +1,85 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+)
+
+//Levels are the severity levels an issue can have, from the lowest to the highest
+var Levels = []string{"info", "warning", "error"}
+
+//LinterSeverity is the severity of the issues of a linter, Level for all of them and
+//Rules for the ones of a given category. In .codenanny it is either a level or a
+//mapping from categories to levels, where the default key sets Level.
+type LinterSeverity struct {
+	Level string
+	Rules map[string]string
+}
+
+//UnmarshalYAML accepts a level or a mapping of categories to levels
+func (s *LinterSeverity) UnmarshalYAML(unmarshal func(interface{}) error) (err error) {
+	if err = unmarshal(&s.Level); err == nil {
+		return nil
+	}
+	var rules map[string]string
+	if err = unmarshal(&rules); err != nil {
+		return fmt.Errorf("severity must be a level or a mapping of rules to levels")
+	}
+	s.Level = rules["default"]
+	delete(rules, "default")
+	s.Rules = rules
+	return nil
+}
+
+//IsLevel tells if level is a known severity level
+func IsLevel(level string) bool {
+	for _, known := range Levels {
+		if level == known {
+			return true
+		}
+	}
+	return false
+}
+
+//CheckSeverities checks the levels set in the severity section of .codenanny
+func (c CodeNannyConfig) CheckSeverities() (err error) {
+	for name, severity := range c.Severity {
+		if severity.Level != "" && !IsLevel(severity.Level) {
+			return fmt.Errorf("the .codenanny file has an unknown severity %q for %s, use one of %v", severity.Level, name, Levels)
+		}
+		for rule, level := range severity.Rules {
+			if !IsLevel(level) {
+				return fmt.Errorf("the .codenanny file has an unknown severity %q for %s rule %s, use one of %v", level, name, rule, Levels)
+			}
+		}
+	}
+	return nil
+}
+
+//SeverityOf returns the level configured for the issues of linter with category, empty when not configured
+func (c CodeNannyConfig) SeverityOf(linter, category string) string {
+	severity, found := c.Severity[linter]
+	if !found {
+		return ""
+	}
+	if level, found := severity.Rules[category]; found && category != "" {
+		return level
+	}
+	return severity.Level
+}
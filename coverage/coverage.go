@@ -0,0 +1,417 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+//Package coverage is the coverage gate: it runs the tests of the changed packages with a coverage profile
+//and reports the packages, and the changed lines, covered less than the thresholds of .codenanny
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lagarciag/codenanny/config"
+	"github.com/lagarciag/codenanny/gotest"
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/linters"
+	"github.com/lagarciag/codenanny/loader"
+	log "github.com/sirupsen/logrus"
+)
+
+//Name names the builtin coverage linter
+const Name = "coverage"
+
+//maxRanges is the number of uncovered ranges listed in the issue of a package
+const maxRanges = 10
+
+//wholeFile are the changed lines of a file git does not track yet
+var wholeFile = LineRange{Start: 1, End: math.MaxInt32}
+
+//LineRange is a range of lines, both ends included
+type LineRange struct {
+	Start int
+	End   int
+}
+
+func (r LineRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+//Block is a block of statements of a coverage profile
+type Block struct {
+	//File is the path of the file relative to the repo root
+	File       string
+	Lines      LineRange
+	Statements int
+	Covered    bool
+}
+
+var (
+	//fileHeader matches the name of the new file in git diff output
+	fileHeader = regexp.MustCompile(`^\+\+\+ (?:b/)?(.+)$`)
+	//hunkHeader matches the new lines of a hunk in git diff output
+	hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+	//profileLine matches a block of a coverage profile: file:startLine.startCol,endLine.endCol statements count
+	profileLine = regexp.MustCompile(`^(.+):(\d+)\.(\d+),(\d+)\.(\d+) (\d+) (\d+)$`)
+)
+
+//SharedProfile returns a temporary file for the builtin test linter to write its coverage profile to, when
+//it runs as well as the builtin coverage linter, so the tests only run once. It is empty otherwise.
+//The caller removes the file.
+func SharedProfile(runner *lint.Runner) (profile string, err error) {
+	if !runner.Config.Coverage.IsSet() || !enabled(runner, linters.ScopeCoverage, Name) || !enabled(runner, linters.ScopeTest, gotest.Name) {
+		return "", nil
+	}
+	return tempProfile()
+}
+
+//enabled tells if the linter called name of scope runs
+func enabled(runner *lint.Runner, scope linters.Scope, name string) bool {
+	for _, linter := range runner.EnabledLinters(scope) {
+		if linter.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+//tempProfile returns the path of a new empty file for a coverage profile
+func tempProfile() (profile string, err error) {
+	file, err := ioutil.TempFile("", "codenanny-cover-")
+	if err != nil {
+		return "", err
+	}
+	file.Close()
+	return file.Name(), nil
+}
+
+//Run runs the enabled coverage linters of runner on the packages of program changed since base,
+//a git revision, HEAD when empty. It does nothing until coverage thresholds are set in .codenanny.
+//When shared is set, it is the profile the test linter wrote, the builtin coverage linter reads it
+//instead of running the tests again.
+func Run(ctx context.Context, runner *lint.Runner, program *loader.Program, base, shared string) (results []lint.Result) {
+	if !runner.Config.Coverage.IsSet() {
+		return results
+	}
+	for _, linter := range runner.EnabledLinters(linters.ScopeCoverage) {
+		start := time.Now()
+		result := lint.Result{Task: lint.Task{Linter: linter}}
+		profile := ""
+		if linter.Name == Name {
+			profile = shared
+		}
+		result.Issues, result.Err = runLinter(ctx, runner, linter, program, base, profile)
+		if result.Err == nil {
+			result.Issues, result.Err = runner.Ignore(linter.Name, result.Issues)
+		}
+		result.Duration = time.Since(start)
+		results = append(results, result)
+	}
+	return results
+}
+
+//runLinter runs a coverage linter on the changed packages of program, or reads profile when set,
+//and returns the coverage shortfalls
+func runLinter(ctx context.Context, runner *lint.Runner, linter linters.Linter, program *loader.Program, base, profile string) (issues []lint.Issue, err error) {
+	changed, err := Changed(ctx, runner.Root, base)
+	if err != nil {
+		return nil, &lint.ToolError{Linter: linter.Name, Reason: "could not find the changed lines", Err: err}
+	}
+	dirs := program.Dirs()
+	var pkgs []string
+	for _, pkg := range program.PkgPaths() {
+		for file := range changed {
+			if filepath.Dir(file) == dirs[pkg] {
+				pkgs = append(pkgs, pkg)
+				break
+			}
+		}
+	}
+	if len(pkgs) == 0 {
+		log.Debug("Coverage: no changed package")
+		return nil, nil
+	}
+
+	var out []byte
+	if profile == "" {
+		if profile, err = tempProfile(); err != nil {
+			return nil, err
+		}
+		defer os.Remove(profile)
+		//Failing tests are reported by the test linter, the profile holds the coverage of the others
+		if out, _, err = runner.RunLinter(ctx, linter, append([]string{profile}, pkgs...)); err != nil {
+			return nil, err
+		}
+	}
+	content, err := ioutil.ReadFile(profile)
+	if err != nil || len(content) == 0 {
+		return nil, &lint.ToolError{Linter: linter.Name, Reason: "wrote no coverage profile", Output: string(out), Err: err}
+	}
+	blocks, err := ParseProfile(content, dirs)
+	if err != nil {
+		return nil, &lint.ToolError{Linter: linter.Name, Reason: "wrote an invalid coverage profile", Output: string(out), Err: err}
+	}
+
+	for _, pkg := range pkgs {
+		dir := dirs[pkg]
+		threshold := runner.Config.Coverage.ThresholdOf(dir)
+		issues = append(issues, check(linter.Name, dir, threshold, blocks[dir], changed)...)
+	}
+	return issues, nil
+}
+
+//check returns the issues of the package in dir when its blocks are covered less than threshold
+func check(name, dir string, threshold config.CoverageThreshold, blocks []Block, changed map[string][]LineRange) (issues []lint.Issue) {
+	percent, uncovered := coverage(blocks)
+	var changedBlocks []Block
+	for _, block := range blocks {
+		if len(intersect(block.Lines, changed[block.File])) > 0 {
+			changedBlocks = append(changedBlocks, block)
+		}
+	}
+	changedPercent, _ := coverage(changedBlocks)
+	log.Infof("Coverage of %s: %.1f%% of the statements, %.1f%% of the changed ones", dir, percent, changedPercent)
+
+	if threshold.Min != nil && percent < *threshold.Min {
+		var ranges []string
+		for _, file := range sortedFiles(uncovered) {
+			for _, lines := range uncovered[file] {
+				ranges = append(ranges, fmt.Sprintf("%s:%s", filepath.Base(file), lines))
+			}
+		}
+		if len(ranges) > maxRanges {
+			ranges = append(ranges[:maxRanges], fmt.Sprintf("and %d more", len(ranges)-maxRanges))
+		}
+		issues = append(issues, lint.Issue{
+			Linter:   name,
+			Path:     dir,
+			Message:  fmt.Sprintf("statement coverage %.1f%% is below %.1f%%, not covered: %s", percent, *threshold.Min, strings.Join(ranges, ", ")),
+			Severity: lint.SeverityError,
+			Category: "package",
+		})
+	}
+
+	if threshold.Changed != nil && changedPercent < *threshold.Changed {
+		for _, file := range sortedFiles(uncovered) {
+			var lines []LineRange
+			for _, uncoveredLines := range uncovered[file] {
+				lines = append(lines, intersect(uncoveredLines, changed[file])...)
+			}
+			for _, lines := range merge(lines) {
+				issues = append(issues, lint.Issue{
+					Linter: name,
+					Path:   file,
+					Line:   lines.Start,
+					Message: fmt.Sprintf("lines %s are changed and not covered, changed lines coverage %.1f%% is below %.1f%%",
+						lines, changedPercent, *threshold.Changed),
+					Severity: lint.SeverityError,
+					Category: "changed",
+				})
+			}
+		}
+	}
+	return issues
+}
+
+//coverage returns the percentage of statements of blocks covered, 100 without statements,
+//and the lines not covered by file
+func coverage(blocks []Block) (percent float64, uncovered map[string][]LineRange) {
+	uncovered = make(map[string][]LineRange)
+	statements, covered := 0, 0
+	for _, block := range blocks {
+		statements += block.Statements
+		if block.Covered {
+			covered += block.Statements
+		} else if block.Statements > 0 {
+			uncovered[block.File] = append(uncovered[block.File], block.Lines)
+		}
+	}
+	for file, lines := range uncovered {
+		uncovered[file] = merge(lines)
+	}
+	if statements == 0 {
+		return 100, uncovered
+	}
+	return 100 * float64(covered) / float64(statements), uncovered
+}
+
+//Changed returns the lines changed since base by file relative to root, as git diff -U0 reports them.
+//The files git does not track yet, and does not ignore, are changed as a whole.
+func Changed(ctx context.Context, root, base string) (changed map[string][]LineRange, err error) {
+	if base == "" {
+		base = "HEAD"
+	}
+	cmd := exec.CommandContext(ctx, "git", "diff", "-U0", "--no-color", "--no-ext-diff", base, "--")
+	cmd.Dir = root
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff %s failed:%s", base, err.Error())
+	}
+	changed = ParseDiff(out)
+
+	cmd = exec.CommandContext(ctx, "git", "ls-files", "--others", "--exclude-standard", "-z")
+	cmd.Dir = root
+	if out, err = cmd.Output(); err != nil {
+		return nil, fmt.Errorf("git ls-files failed:%s", err.Error())
+	}
+	for _, file := range strings.Split(string(out), "\x00") {
+		if file != "" {
+			changed[filepath.FromSlash(file)] = []LineRange{wholeFile}
+		}
+	}
+	return changed, nil
+}
+
+//ParseDiff returns the lines added or changed by a diff by file, deleted files are left out
+func ParseDiff(diff []byte) (changed map[string][]LineRange) {
+	changed = make(map[string][]LineRange)
+	var file string
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := fileHeader.FindStringSubmatch(line); match != nil {
+			file = filepath.FromSlash(match[1])
+			if match[1] == "/dev/null" {
+				file = ""
+			}
+			continue
+		}
+		match := hunkHeader.FindStringSubmatch(line)
+		if match == nil || file == "" {
+			continue
+		}
+		start, _ := strconv.Atoi(match[1])
+		count := 1
+		if match[2] != "" {
+			count, _ = strconv.Atoi(match[2])
+		}
+		if count > 0 {
+			changed[file] = append(changed[file], LineRange{Start: start, End: start + count - 1})
+		}
+	}
+	return changed
+}
+
+//ParseProfile returns the blocks of a coverage profile by package dir, dirs holding the dir of each package by import path.
+//Blocks found several times, once by test binary, are covered when any of them is.
+func ParseProfile(profile []byte, dirs map[string]string) (blocks map[string][]Block, err error) {
+	type key struct {
+		file                                 string
+		startLine, startCol, endLine, endCol int
+	}
+	index := make(map[key]int)
+	var all []Block
+	scanner := bufio.NewScanner(bytes.NewReader(profile))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		match := profileLine.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("invalid coverage profile line %q", line)
+		}
+		dir, found := dirs[path.Dir(match[1])]
+		if !found {
+			continue
+		}
+		var numbers [6]int
+		for id := range numbers {
+			numbers[id], _ = strconv.Atoi(match[id+2])
+		}
+		block := Block{
+			File:       filepath.Join(dir, path.Base(match[1])),
+			Lines:      LineRange{Start: numbers[0], End: numbers[2]},
+			Statements: numbers[4],
+			Covered:    numbers[5] > 0,
+		}
+		k := key{block.File, numbers[0], numbers[1], numbers[2], numbers[3]}
+		if id, found := index[k]; found {
+			all[id].Covered = all[id].Covered || block.Covered
+			continue
+		}
+		index[k] = len(all)
+		all = append(all, block)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	blocks = make(map[string][]Block)
+	for _, block := range all {
+		dir := filepath.Dir(block.File)
+		blocks[dir] = append(blocks[dir], block)
+	}
+	return blocks, nil
+}
+
+//intersect returns the parts of ranges inside lines
+func intersect(lines LineRange, ranges []LineRange) (parts []LineRange) {
+	for _, other := range ranges {
+		part := LineRange{Start: other.Start, End: other.End}
+		if part.Start < lines.Start {
+			part.Start = lines.Start
+		}
+		if part.End > lines.End {
+			part.End = lines.End
+		}
+		if part.Start <= part.End {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+//merge sorts ranges and merges the ones that overlap or touch
+func merge(ranges []LineRange) (merged []LineRange) {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	for _, lines := range ranges {
+		if last := len(merged) - 1; last >= 0 && lines.Start <= merged[last].End+1 {
+			if lines.End > merged[last].End {
+				merged[last].End = lines.End
+			}
+			continue
+		}
+		merged = append(merged, lines)
+	}
+	return merged
+}
+
+//sortedFiles returns the files of uncovered sorted
+func sortedFiles(uncovered map[string][]LineRange) (files []string) {
+	for file := range uncovered {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
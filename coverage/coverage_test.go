@@ -0,0 +1,177 @@
+package coverage_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/lagarciag/codenanny/config"
+	"github.com/lagarciag/codenanny/coverage"
+	"github.com/lagarciag/codenanny/gotest"
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/loader"
+)
+
+func TestMain(t *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	formatter := &log.TextFormatter{}
+	formatter.ForceColors = true
+	formatter.DisableTimestamp = true
+	log.SetFormatter(formatter)
+	v := t.Run()
+	os.Exit(v)
+
+}
+
+func TestParseDiff(t *testing.T) {
+	diff := strings.Join([]string{
+		"diff --git a/a/a.go b/a/a.go",
+		"--- a/a/a.go",
+		"+++ b/a/a.go",
+		"@@ -3 +3,2 @@ func A() {",
+		"@@ -10,2 +11,0 @@ func B() {",
+		"@@ -20,0 +20 @@ func C() {",
+		"diff --git a/old.go b/old.go",
+		"--- a/old.go",
+		"+++ /dev/null",
+		"@@ -1,3 +0,0 @@",
+		"",
+	}, "\n")
+	changed := coverage.ParseDiff([]byte(diff))
+	expected := map[string][]coverage.LineRange{
+		filepath.Join("a", "a.go"): {{Start: 3, End: 4}, {Start: 20, End: 20}},
+	}
+	if !reflect.DeepEqual(changed, expected) {
+		t.Error("Unexpected changed lines:", changed)
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	profile := strings.Join([]string{
+		"mode: set",
+		"example.com/nanny/a/a.go:3.14,5.2 2 0",
+		"example.com/nanny/a/a.go:3.14,5.2 2 1",
+		"example.com/nanny/a/a.go:7.14,9.2 1 0",
+		"example.com/other/o.go:1.1,2.2 1 1",
+		"",
+	}, "\n")
+	blocks, err := coverage.ParseProfile([]byte(profile), map[string]string{"example.com/nanny/a": "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join("a", "a.go")
+	expected := map[string][]coverage.Block{"a": {
+		{File: file, Lines: coverage.LineRange{Start: 3, End: 5}, Statements: 2, Covered: true},
+		{File: file, Lines: coverage.LineRange{Start: 7, End: 9}, Statements: 1},
+	}}
+	if !reflect.DeepEqual(blocks, expected) {
+		t.Error("Unexpected blocks:", blocks)
+	}
+	if _, err = coverage.ParseProfile([]byte("mode: set\nnot a block\n"), nil); err == nil {
+		t.Error("Invalid profiles must be rejected")
+	}
+}
+
+//git runs git in dir
+func git(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=nanny", "-c", "user.email=nanny@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatal(string(out), err)
+	}
+}
+
+//write writes the files of a map in root
+func write(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	root := t.TempDir()
+	write(t, root, map[string]string{
+		"go.mod":              "module example.com/nanny\n\ngo 1.16\n",
+		"calc/calc.go":        "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n",
+		"calc/calc_test.go":   "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fail()\n\t}\n}\n",
+		"other/other.go":      "package other\n\nfunc Other() int {\n\treturn 1\n}\n",
+		"other/other_test.go": "package other\n",
+	})
+	git(t, root, "init", "-q")
+	git(t, root, "add", "-A")
+	git(t, root, "commit", "-q", "-m", "initial")
+	//Sub is changed and not tested, other is not changed
+	write(t, root, map[string]string{
+		"calc/calc.go": "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc Sub(a, b int) int {\n\treturn a - b\n}\n",
+	})
+
+	runner, err := lint.NewRunner(root, config.CodeNannyConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := loader.Load(context.Background(), root, []string{"./..."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results := coverage.Run(context.Background(), runner, program, "", ""); len(results) != 0 {
+		t.Error("The coverage gate must only run with thresholds:", results)
+	}
+
+	min, changed := 60.0, 100.0
+	runner.Config.Coverage = config.Coverage{CoverageThreshold: config.CoverageThreshold{Min: &min, Changed: &changed}}
+	results := coverage.Run(context.Background(), runner, program, "", "")
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatal("Unexpected results:", results)
+	}
+	file := filepath.Join("calc", "calc.go")
+	expected := []string{
+		"calc: statement coverage 50.0% is below 60.0%, not covered: calc.go:8-9",
+		file + ":8: lines 8-9 are changed and not covered, changed lines coverage 0.0% is below 100.0%",
+	}
+	issues := results[0].Issues
+	if len(issues) != len(expected) {
+		t.Fatal("Unexpected issues:", issues)
+	}
+	for id, issue := range issues {
+		if issue.String() != expected[id] {
+			t.Errorf("Expected %q, got %q", expected[id], issue.String())
+		}
+	}
+
+	//With the test linter on, the tests run once and the coverage gate reads their profile
+	runner.Config.Enabled = map[string]bool{gotest.Name: true}
+	profile, err := coverage.SharedProfile(runner)
+	if err != nil || profile == "" {
+		t.Fatal("The test linter must share its profile:", profile, err)
+	}
+	defer os.Remove(profile)
+	if tests := gotest.Run(context.Background(), runner, program, profile); len(tests) != 1 || tests[0].Tests.Tests.Passed != 1 {
+		t.Fatal("Unexpected test results:", tests)
+	}
+	shared := coverage.Run(context.Background(), runner, program, "", profile)
+	if len(shared) != 1 || !reflect.DeepEqual(shared[0].Issues, issues) {
+		t.Error("The shared profile must give the same issues:", shared)
+	}
+
+	//New files are changed as a whole
+	write(t, root, map[string]string{"other/new.go": "package other\n\nfunc New() int {\n\treturn 2\n}\n"})
+	lines, err := coverage.Changed(context.Background(), root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranges := lines[filepath.Join("other", "new.go")]; len(ranges) != 1 || ranges[0].Start != 1 || ranges[0].End < 5 {
+		t.Error("Untracked files must be changed:", lines)
+	}
+}
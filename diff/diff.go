@@ -0,0 +1,221 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+//Package diff computes unified diffs that git apply and patch understand
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+//Context is the number of unchanged lines shown around the changes
+const Context = 3
+
+//op is what an edit does with a line
+type op int
+
+const (
+	equal op = iota
+	insert
+	remove
+)
+
+//edit is a line of an edit script
+type edit struct {
+	op   op
+	line string
+}
+
+//Unified returns the unified diff turning old into new, with the git headers naming path in both sides.
+//It is empty when old and new are the same.
+func Unified(path string, old, new []byte) string {
+	if bytes.Equal(old, new) {
+		return ""
+	}
+	path = filepath.ToSlash(path)
+	script := editScript(splitLines(old), splitLines(new))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", path, path, path, path)
+	//oldLine and newLine count the lines of each side before script[id]
+	oldLine, newLine := 0, 0
+	for id := 0; id < len(script); {
+		change := nextChange(script, id)
+		if change == len(script) {
+			break
+		}
+		start := change - Context
+		if start < id {
+			start = id
+		}
+		for ; id < start; id++ {
+			oldLine++
+			newLine++
+		}
+		end := hunkEnd(script, change)
+
+		var body strings.Builder
+		oldCount, newCount := 0, 0
+		for _, e := range script[start:end] {
+			switch e.op {
+			case equal:
+				writeLine(&body, ' ', e.line)
+				oldCount++
+				newCount++
+			case remove:
+				writeLine(&body, '-', e.line)
+				oldCount++
+			case insert:
+				writeLine(&body, '+', e.line)
+				newCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+		out.WriteString(body.String())
+		oldLine += oldCount
+		newLine += newCount
+		id = end
+	}
+	return out.String()
+}
+
+//nextChange returns the index of the first edit of script from id on that is not equal, len(script) if none
+func nextChange(script []edit, id int) int {
+	for ; id < len(script); id++ {
+		if script[id].op != equal {
+			return id
+		}
+	}
+	return id
+}
+
+//hunkEnd returns the end of the hunk holding the change at index change of script.
+//Changes closer than twice the context share the hunk.
+func hunkEnd(script []edit, change int) int {
+	last := change
+	for id := change + 1; id < len(script); id++ {
+		if script[id].op != equal {
+			last = id
+		} else if id-last > 2*Context {
+			break
+		}
+	}
+	end := last + 1 + Context
+	if end > len(script) {
+		end = len(script)
+	}
+	return end
+}
+
+//hunkRange formats the range of a hunk side, after is the number of lines before the hunk
+func hunkRange(after, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", after)
+	}
+	return fmt.Sprintf("%d,%d", after+1, count)
+}
+
+//writeLine writes a line of a hunk, marking the last line of a file that does not end with a newline
+func writeLine(out *strings.Builder, mark byte, line string) {
+	out.WriteByte(mark)
+	out.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		out.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+//splitLines splits text after each newline, the last line has none when text does not end with one
+func splitLines(text []byte) (lines []string) {
+	for len(text) > 0 {
+		end := bytes.IndexByte(text, '\n') + 1
+		if end == 0 {
+			end = len(text)
+		}
+		lines = append(lines, string(text[:end]))
+		text = text[end:]
+	}
+	return lines
+}
+
+//editScript returns the shortest edit script turning a into b, using the Myers O(ND) algorithm
+func editScript(a, b []string) (script []edit) {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	//v holds the furthest x reached on each diagonal k = x - y, at v[offset+k]
+	v := make([]int, 2*max+3)
+	//trace[d] keeps v[-d..d] as it was before step d, to walk the path back
+	var trace [][]int
+	for d, done := 0, false; d <= max && !done; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+	}
+
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || k != d && prev[d+k-1] < prev[d+k+1] {
+			prevK = k + 1
+		}
+		prevX := prev[d+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			script = append(script, edit{equal, a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			script = append(script, edit{insert, b[y-1]})
+			y--
+		} else {
+			script = append(script, edit{remove, a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		script = append(script, edit{equal, a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(script)-1; i < j; i, j = i+1, j-1 {
+		script[i], script[j] = script[j], script[i]
+	}
+	return script
+}
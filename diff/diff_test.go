@@ -0,0 +1,83 @@
+package diff_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/lagarciag/codenanny/diff"
+)
+
+func TestMain(t *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	log.SetFormatter(&log.TextFormatter{})
+	v := t.Run()
+	os.Exit(v)
+}
+
+func TestUnified(t *testing.T) {
+	old := "package a\nfunc  A( ) {\n}\n"
+	new := "package a\n\nfunc A() {\n}\n"
+	expected := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n" +
+		"@@ -1,3 +1,4 @@\n package a\n-func  A( ) {\n+\n+func A() {\n }\n"
+	if got := diff.Unified("a.go", []byte(old), []byte(new)); got != expected {
+		t.Errorf("Unexpected diff:\n%s", got)
+	}
+	if got := diff.Unified("a.go", []byte(old), []byte(old)); got != "" {
+		t.Errorf("Same contents must have no diff:\n%s", got)
+	}
+	got := diff.Unified("a.go", []byte("a\nb"), []byte("a\nc"))
+	if !strings.Contains(got, "-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n") {
+		t.Errorf("Missing final newlines must be marked:\n%s", got)
+	}
+}
+
+func TestUnifiedHunks(t *testing.T) {
+	var lines []string
+	for id := 0; id < 30; id++ {
+		lines = append(lines, strings.Repeat("x", id))
+	}
+	old := strings.Join(lines, "\n") + "\n"
+	lines[2], lines[25] = "changed", "changed"
+	new := strings.Join(lines, "\n") + "\n"
+	got := diff.Unified("a.go", []byte(old), []byte(new))
+	if strings.Count(got, "@@ -") != 2 || !strings.Contains(got, "@@ -1,6 +1,6 @@") ||
+		!strings.Contains(got, "@@ -23,7 +23,7 @@") {
+		t.Errorf("Distant changes must be in separate hunks:\n%s", got)
+	}
+}
+
+func TestUnifiedGitApply(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	cases := map[string][2]string{
+		"insert.go":  {"a\nb\nc\n", "a\nb\nnew\nc\n"},
+		"remove.go":  {"a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n", "a\nc\nd\ne\nf\ng\nh\ni\n"},
+		"empty.go":   {"", "package empty\n"},
+		"nonl.go":    {"a\nb", "a\nb\n"},
+		"replace.go": {"one\ntwo\nthree\n", "uno\ndos\ntres\n"},
+	}
+	dir := t.TempDir()
+	var patch string
+	for name, contents := range cases {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents[0]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		patch += diff.Unified(name, []byte(contents[0]), []byte(contents[1]))
+	}
+	apply := exec.Command("git", "apply", "-")
+	apply.Dir = dir
+	apply.Stdin = strings.NewReader(patch)
+	if out, err := apply.CombinedOutput(); err != nil {
+		t.Fatalf("git apply failed:%s\n%s\n%s", err, out, patch)
+	}
+	for name, contents := range cases {
+		if content, _ := os.ReadFile(filepath.Join(dir, name)); string(content) != contents[1] {
+			t.Errorf("%s patched to %q, expected %q", name, content, contents[1])
+		}
+	}
+}
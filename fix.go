@@ -0,0 +1,308 @@
+// Copyright © 2016 Luis Garcia <luis.a.garcia@hpe.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package codenanny
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lagarciag/codenanny/analyzers"
+	"github.com/lagarciag/codenanny/build"
+	"github.com/lagarciag/codenanny/diff"
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/loader"
+	"github.com/lagarciag/codenanny/parser"
+	"github.com/lagarciag/codenanny/suppress"
+	log "github.com/sirupsen/logrus"
+)
+
+//FixReport is the result of a fix run
+type FixReport struct {
+	//Root is the directory the fixers ran in
+	Root string
+	//Changed are the files the fixers rewrote, or would rewrite with Options.Diff, relative to Root and sorted
+	Changed []string
+	//Diff is the unified diff of the changes when Options.Diff is set, git apply takes it as is
+	Diff string
+	//Fixers reports every fixer that ran sorted by name
+	Fixers []LinterReport
+	//Duration is the wall time of the run
+	Duration time.Duration
+	//Verdict is pass when every fixer ran, error when some could not
+	Verdict Verdict
+}
+
+//Fix runs the fixers of the enabled linters on the files selected by opts and reports the files they changed.
+//The fixers are the linters with FixArgs, run with them in place of their Args, and the suggested fixes of the analyzers.
+//With opts.Diff the files are left untouched and the report tells what the fixers would change.
+//Errors are reported the way Run does.
+func Fix(ctx context.Context, opts Options) (report *FixReport, err error) {
+	start := time.Now()
+	runner, files, err := prepare(opts)
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	var patch string
+	var results []lint.Result
+	if opts.Diff {
+		changed, patch, results, err = previewFixes(ctx, runner, files, parser.Dirs(files))
+	} else {
+		changed, results, err = fixFiles(ctx, runner, files, parser.Dirs(files))
+	}
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+
+	//Only the missing fixers matter here
+	for name := range runner.Disabled {
+		if linter, found := runner.Registry.Get(name); !found || !linter.CanFix() {
+			delete(runner.Disabled, name)
+		}
+	}
+	lintReport := newReport(runner, results, ctx.Err() != nil, lint.SeverityError, time.Since(start))
+	report = &FixReport{
+		Root:     runner.Root,
+		Changed:  changed,
+		Diff:     patch,
+		Fixers:   lintReport.Linters,
+		Duration: lintReport.Duration,
+		Verdict:  lintReport.Verdict,
+	}
+	if opts.Output != nil {
+		if err = report.WriteText(opts.Output); err != nil {
+			return report, err
+		}
+	}
+	return report, ctx.Err()
+}
+
+//WriteText writes the changed files to w, one per line
+func (r *FixReport) WriteText(w io.Writer) (err error) {
+	for _, path := range r.Changed {
+		if _, err = fmt.Fprintln(w, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//fixFiles runs the fixers of runner on files and returns the files they changed, relative to the runner root.
+//The results tell how each fixer did, they carry no issues: what could be fixed is not reported.
+func fixFiles(ctx context.Context, runner *lint.Runner, files, dirList []string) (changed []string, results []lint.Result, err error) {
+	before, err := snapshot(runner.Root, dirList)
+	if err != nil {
+		return nil, nil, err
+	}
+	program, err := loader.Load(ctx, runner.Root, loader.DirPatterns(dirList))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not find the packages to fix:%s", err.Error())
+	}
+
+	//The fixers run one at a time, several of them rewrite the same files
+	_, broken := build.Check(program)
+	tasks, skipped := broken.Skip(lint.FixTasks(runner.AllTasks(files, dirList, program.PkgPaths())))
+	serial := *runner
+	serial.Jobs = 1
+	results = append(serial.Execute(ctx, tasks), skipped...)
+	if ctx.Err() != nil {
+		return nil, results, ctx.Err()
+	}
+
+	//The analyzers fix the code left by the external fixers
+	if len(tasks) > 0 {
+		if program, err = loader.Load(ctx, runner.Root, loader.DirPatterns(dirList)); err != nil {
+			return nil, results, fmt.Errorf("could not find the packages to fix:%s", err.Error())
+		}
+	}
+	analysisResults := analyzers.Run(ctx, runner, program.Roots())
+	if ctx.Err() != nil {
+		return nil, append(results, analysisResults...), ctx.Err()
+	}
+	//The issues suppressed by the directives of the code are left as they are
+	var issues []lint.Issue
+	for _, result := range suppress.Filter(runner.Root, nil, runner.Config, analysisResults) {
+		issues = append(issues, result.Issues...)
+	}
+	if _, err = lint.ApplyFixes(runner.Root, issues); err != nil {
+		return nil, results, fmt.Errorf("could not apply the suggested fixes:%s", err.Error())
+	}
+	results = append(results, analysisResults...)
+	for id := range results {
+		results[id].Issues = nil
+	}
+
+	after, err := snapshot(runner.Root, dirList)
+	if err != nil {
+		return nil, results, err
+	}
+	for path, sum := range after {
+		if before[path] != sum {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed, results, nil
+}
+
+//ToolErrors returns the tool errors of every fixer, with the raw output of the fixers that ran
+func (r *FixReport) ToolErrors() (toolErrors []*lint.ToolError) {
+	return toolErrorsOf(r.Fixers)
+}
+
+//previewFixes runs the fixers of runner on the content of files in memory and returns the files they would change,
+//relative to the runner root, with the unified diff of the changes. Nothing is written: the external fixers read the
+//files on their standard input, the analyzers see the fixed files through an overlay. The fixers that can't tell what
+//they would change are left out.
+func previewFixes(ctx context.Context, runner *lint.Runner, files, dirList []string) (changed []string, patch string, results []lint.Result, err error) {
+	original, err := readGoFiles(runner.Root, dirList)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	fixed := make(map[string][]byte, len(original))
+	for path, content := range original {
+		fixed[path] = content
+	}
+	program, err := loader.Load(ctx, runner.Root, loader.DirPatterns(dirList))
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("could not find the packages to fix:%s", err.Error())
+	}
+
+	_, broken := build.Check(program)
+	tasks, skipped := broken.Skip(lint.FixTasks(runner.AllTasks(files, dirList, program.PkgPaths())))
+	results = skipped
+	for _, task := range tasks {
+		if !task.Linter.CanPreview() {
+			log.Warnf("%s can't tell what it would fix, its fixes are left out of the diff", task.Linter.Name)
+			continue
+		}
+		start := time.Now()
+		result := lint.Result{Task: task}
+		for _, target := range task.Targets {
+			path := filepath.Clean(target)
+			content, found := fixed[path]
+			if !found {
+				if content, result.Err = ioutil.ReadFile(filepath.Join(runner.Root, path)); result.Err != nil {
+					break
+				}
+				original[path] = content
+			}
+			if content, result.Err = runner.PreviewFix(ctx, task.Linter, path, content); result.Err != nil {
+				break
+			}
+			fixed[path] = content
+		}
+		result.Duration = time.Since(start)
+		results = append(results, result)
+		if ctx.Err() != nil {
+			return nil, "", results, ctx.Err()
+		}
+	}
+
+	//The analyzers fix the code left by the external fixers
+	overlay := make(map[string][]byte)
+	for path, content := range fixed {
+		if !bytes.Equal(content, original[path]) {
+			overlay[path] = content
+		}
+	}
+	if program, err = loader.LoadOverlay(ctx, runner.Root, loader.DirPatterns(dirList), overlay); err != nil {
+		return nil, "", results, fmt.Errorf("could not find the packages to fix:%s", err.Error())
+	}
+	analysisResults := analyzers.Run(ctx, runner, program.Roots())
+	if ctx.Err() != nil {
+		return nil, "", append(results, analysisResults...), ctx.Err()
+	}
+	var issues []lint.Issue
+	for _, result := range suppress.Filter(runner.Root, fixed, runner.Config, analysisResults) {
+		issues = append(issues, result.Issues...)
+	}
+	suggested, err := lint.FixContents(runner.Root, fixed, issues)
+	if err != nil {
+		return nil, "", results, fmt.Errorf("could not apply the suggested fixes:%s", err.Error())
+	}
+	results = append(results, analysisResults...)
+	for id := range results {
+		results[id].Issues = nil
+	}
+	//The suggested fixes may change files outside dirList
+	for _, path := range suggested {
+		if _, found := original[path]; !found {
+			if original[path], err = ioutil.ReadFile(filepath.Join(runner.Root, path)); err != nil {
+				return nil, "", results, err
+			}
+		}
+	}
+
+	for path, content := range fixed {
+		if !bytes.Equal(content, original[path]) {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	var out strings.Builder
+	for _, path := range changed {
+		out.WriteString(diff.Unified(path, original[path], fixed[path]))
+	}
+	return changed, out.String(), results, nil
+}
+
+//readGoFiles returns the content of every go file of dirList, by path relative to root
+func readGoFiles(root string, dirList []string) (contents map[string][]byte, err error) {
+	contents = make(map[string][]byte)
+	for _, dir := range dirList {
+		entries, err := ioutil.ReadDir(filepath.Join(root, dir))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if contents[path], err = ioutil.ReadFile(filepath.Join(root, path)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return contents, nil
+}
+
+//snapshot returns the checksum of every go file of dirList, by path relative to root
+func snapshot(root string, dirList []string) (sums map[string][sha256.Size]byte, err error) {
+	contents, err := readGoFiles(root, dirList)
+	if err != nil {
+		return nil, err
+	}
+	sums = make(map[string][sha256.Size]byte, len(contents))
+	for path, content := range contents {
+		sums[path] = sha256.Sum256(content)
+	}
+	return sums, nil
+}
@@ -0,0 +1,253 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+//Package gotest runs the test linters, go test -json on the linted packages, and turns the failing tests into issues
+package gotest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/linters"
+	"github.com/lagarciag/codenanny/loader"
+)
+
+//Name names the builtin test linter
+const Name = "test"
+
+//event is a line printed by go test -json, see go doc test2json
+type event struct {
+	Action     string
+	Package    string
+	ImportPath string
+	Test       string
+	Output     string
+	//FailedBuild is set on the fail event of a package that did not build
+	FailedBuild string
+}
+
+var (
+	//location matches the file:line: message lines written by t.Error and friends
+	location = regexp.MustCompile(`^\s+(\S+\.go):(\d+): ?(.*)$`)
+	//assertLocation matches the location of a testify assertion, Location in old versions
+	assertLocation = regexp.MustCompile(`^\s+(?:Error Trace|Location):\s+(\S+\.go):(\d+)\s*$`)
+	//assertError matches the message of a testify assertion
+	assertError = regexp.MustCompile(`^\s+Error:\s+(.*)$`)
+	//frame matches the file:line of a stack frame
+	frame = regexp.MustCompile(`^\t(\S+\.go):(\d+)(?: \+0x[0-9a-f]+)?$`)
+)
+
+//Run runs the enabled test linters of runner on the packages of program.
+//It returns one result per linter, with the issues and the test counts.
+//When profile is set the builtin test linter also writes the coverage profile of the packages to it.
+func Run(ctx context.Context, runner *lint.Runner, program *loader.Program, profile string) (results []lint.Result) {
+	pkgs := program.PkgPaths()
+	if len(pkgs) == 0 {
+		return results
+	}
+	dirs := program.Dirs()
+	for _, linter := range runner.EnabledLinters(linters.ScopeTest) {
+		start := time.Now()
+		result := lint.Result{Task: lint.Task{Linter: linter, Targets: pkgs}}
+		targets := pkgs
+		if profile != "" && linter.Name == Name {
+			targets = append([]string{"-covermode=set", "-coverprofile=" + profile}, pkgs...)
+		}
+		out, failed, err := runner.RunLinter(ctx, linter, targets)
+		if err == nil {
+			var issues []lint.Issue
+			issues, result.Tests, err = Parse(linter, runner.Root, dirs, out, failed)
+			if err == nil {
+				issues, err = runner.Ignore(linter.Name, issues)
+			}
+			result.Issues = issues
+		}
+		result.Err = err
+		result.Duration = time.Since(start)
+		results = append(results, result)
+	}
+	return results
+}
+
+//test gathers the output of a test, or of a package when name is empty
+type test struct {
+	pkg    string
+	name   string
+	output []string
+}
+
+//Parse turns the go test -json output of linter into issues and counts, dirs holding the
+//directory of each package relative to root. Lines that are not events, like build errors
+//of old go versions, are parsed with the linter pattern. When failed is set and nothing
+//explains why the output is returned in a *lint.ToolError.
+func Parse(linter linters.Linter, root string, dirs map[string]string, out []byte, failed bool) (issues []lint.Issue, summary lint.TestSummary, err error) {
+	tests := make(map[string]*test)
+	//failedTests are in the order they failed, subtests before their parents
+	var failedTests, failedPkgs []*test
+	var other bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var e event
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &e) != nil {
+			other.Write(line)
+			other.WriteByte('\n')
+			continue
+		}
+		if e.Action == "build-output" {
+			other.WriteString(e.Output)
+			continue
+		}
+		key := e.Package + " " + e.Test
+		t, found := tests[key]
+		if !found {
+			t = &test{pkg: e.Package, name: e.Test}
+			tests[key] = t
+		}
+		switch {
+		case e.Action == "output":
+			t.output = append(t.output, e.Output)
+		case e.Test == "":
+			//Packages that did not build are explained by the build output
+			if countAction(&summary.Packages, e.Action) && e.Action == "fail" && e.FailedBuild == "" {
+				failedPkgs = append(failedPkgs, t)
+			}
+		default:
+			if countAction(&summary.Tests, e.Action) && e.Action == "fail" {
+				failedTests = append(failedTests, t)
+			}
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, summary, err
+	}
+
+	//explained tells the tests and packages with a failure reported, by package and name
+	explained := make(map[string]bool)
+	for _, t := range failedTests {
+		testIssues, located := failure(linter.Name, root, dirs[t.pkg], t)
+		//A test fails with its subtests, it is only reported when it tells where it failed
+		if located || !explained[t.pkg+" "+t.name+"/"] {
+			issues = append(issues, testIssues...)
+		}
+		explained[t.pkg+" "+parent(t.name)+"/"] = true
+	}
+	for _, t := range failedPkgs {
+		if !explained[t.pkg+" /"] {
+			testIssues, _ := failure(linter.Name, root, dirs[t.pkg], t)
+			issues = append(issues, testIssues...)
+		}
+	}
+
+	buildIssues, _ := lint.ParseOutput(linter, other.Bytes(), false)
+	issues = append(issues, buildIssues...)
+	if failed && len(issues) == 0 {
+		return nil, summary, &lint.ToolError{Linter: linter.Name, Reason: "failed and no failing test was found", Output: string(out)}
+	}
+	return issues, summary, nil
+}
+
+//countAction counts a pass, fail or skip action, returning false for the other actions
+func countAction(counts *lint.TestCounts, action string) bool {
+	switch action {
+	case "pass":
+		counts.Passed++
+	case "fail":
+		counts.Failed++
+	case "skip":
+		counts.Skipped++
+	default:
+		return false
+	}
+	return true
+}
+
+//parent returns the name of the test running the subtest name, the empty string for top level tests
+func parent(name string) string {
+	if id := strings.LastIndex(name, "/"); id >= 0 {
+		return name[:id]
+	}
+	return ""
+}
+
+//failure returns the issues explaining why the test or package t failed, dir being the directory of its package.
+//located is false when t did not tell where it failed, it gets a single issue on its package then.
+func failure(name, root, dir string, t *test) (issues []lint.Issue, located bool) {
+	var current *lint.Issue
+	newIssue := func(path, line, message string) {
+		if filepath.IsAbs(path) {
+			path = lint.RelPath(root, path)
+		} else {
+			path = filepath.Join(dir, path)
+		}
+		issue := lint.Issue{Linter: name, Path: path, Message: message, Severity: lint.SeverityError}
+		issue.Line, _ = strconv.Atoi(line)
+		issues = append(issues, issue)
+		current = &issues[len(issues)-1]
+	}
+
+	var panicked string
+	for _, output := range t.output {
+		for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+			if match := assertLocation.FindStringSubmatch(line); match != nil {
+				//testify writes its location after the one of the t.Error call it makes
+				if current != nil && current.Message == "" {
+					issues = issues[:len(issues)-1]
+				}
+				newIssue(match[1], match[2], "")
+			} else if match := assertError.FindStringSubmatch(line); match != nil && current != nil && current.Message == "" {
+				current.Message = strings.TrimSpace(match[1])
+			} else if strings.HasPrefix(line, "panic: ") && panicked == "" {
+				panicked = line
+			} else if match := frame.FindStringSubmatch(line); match != nil && panicked != "" {
+				if rel, err := filepath.Rel(root, match[1]); err == nil && filepath.Dir(rel) == dir {
+					newIssue(match[1], match[2], panicked)
+					panicked = ""
+				}
+			} else if match := location.FindStringSubmatch(line); match != nil && panicked == "" {
+				newIssue(match[1], match[2], strings.TrimSpace(match[3]))
+			}
+		}
+	}
+
+	located = len(issues) > 0
+	if !located {
+		message := "failed"
+		if panicked != "" {
+			message = panicked
+		}
+		issues = append(issues, lint.Issue{Linter: name, Path: dir, Message: message, Severity: lint.SeverityError})
+	}
+	prefix := t.name
+	if prefix == "" {
+		prefix = t.pkg
+	}
+	for id := range issues {
+		issues[id].Message = fmt.Sprintf("%s: %s", prefix, issues[id].Message)
+	}
+	return issues, located
+}
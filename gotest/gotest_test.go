@@ -0,0 +1,143 @@
+package gotest_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/lagarciag/codenanny/config"
+	"github.com/lagarciag/codenanny/gotest"
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/linters"
+	"github.com/lagarciag/codenanny/loader"
+)
+
+func TestMain(t *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	formatter := &log.TextFormatter{}
+	formatter.ForceColors = true
+	formatter.DisableTimestamp = true
+	log.SetFormatter(formatter)
+	v := t.Run()
+	os.Exit(v)
+
+}
+
+// events joins go test -json lines
+func events(lines ...string) []byte {
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+func TestParse(t *testing.T) {
+	test, _ := linters.Get("test")
+	dirs := map[string]string{"example.com/nanny/a": "a", "example.com/nanny/b": "b"}
+	out := events(
+		`{"Action":"output","Package":"example.com/nanny/a","Test":"TestA","Output":"    a_test.go:6: boom\n"}`,
+		`{"Action":"output","Package":"example.com/nanny/a","Test":"TestA/sub","Output":"    a_test.go:7: sub 1\n"}`,
+		`{"Action":"fail","Package":"example.com/nanny/a","Test":"TestA/sub"}`,
+		`{"Action":"fail","Package":"example.com/nanny/a","Test":"TestA"}`,
+		`{"Action":"output","Package":"example.com/nanny/a","Test":"TestEqual","Output":"    a_test.go:12: \n"}`,
+		`{"Action":"output","Package":"example.com/nanny/a","Test":"TestEqual","Output":"        \tError Trace:\t/repo/a/a_test.go:12\n"}`,
+		`{"Action":"output","Package":"example.com/nanny/a","Test":"TestEqual","Output":"        \tError:      \tNot equal: \n"}`,
+		`{"Action":"output","Package":"example.com/nanny/a","Test":"TestEqual","Output":"        \t            \texpected: 1\n"}`,
+		`{"Action":"fail","Package":"example.com/nanny/a","Test":"TestEqual"}`,
+		`{"Action":"output","Package":"example.com/nanny/a","Test":"TestPanic","Output":"panic: assignment to entry in nil map\n"}`,
+		`{"Action":"output","Package":"example.com/nanny/a","Test":"TestPanic","Output":"\t/usr/local/go/src/testing/testing.go:2123 +0x232\n"}`,
+		`{"Action":"output","Package":"example.com/nanny/a","Test":"TestPanic","Output":"\t/repo/a/a_test.go:20 +0x28\n"}`,
+		`{"Action":"fail","Package":"example.com/nanny/a","Test":"TestPanic"}`,
+		`{"Action":"pass","Package":"example.com/nanny/a","Test":"TestOK"}`,
+		`{"Action":"skip","Package":"example.com/nanny/a","Test":"TestSkip"}`,
+		`{"Action":"fail","Package":"example.com/nanny/a"}`,
+		`{"ImportPath":"example.com/nanny/b","Action":"build-output","Output":"# example.com/nanny/b\n"}`,
+		`{"ImportPath":"example.com/nanny/b","Action":"build-output","Output":"b/b.go:3:23: undefined: x\n"}`,
+		`{"Action":"fail","Package":"example.com/nanny/b","FailedBuild":"example.com/nanny/b"}`,
+		`{"Action":"skip","Package":"example.com/nanny/c"}`,
+	)
+
+	issues, summary, err := gotest.Parse(test, "/repo", dirs, out, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"a/a_test.go:7: TestA/sub: sub 1",
+		"a/a_test.go:6: TestA: boom",
+		"a/a_test.go:12: TestEqual: Not equal:",
+		"a/a_test.go:20: TestPanic: panic: assignment to entry in nil map",
+		"b/b.go:3:23: undefined: x",
+	}
+	if len(issues) != len(expected) {
+		t.Fatal("Unexpected issues:", issues)
+	}
+	for id, issue := range issues {
+		if issue.String() != expected[id] || issue.Linter != "test" {
+			t.Errorf("Expected %q, got %q", expected[id], issue.String())
+		}
+	}
+	want := lint.TestSummary{
+		Packages: lint.TestCounts{Failed: 2, Skipped: 1},
+		Tests:    lint.TestCounts{Passed: 1, Failed: 4, Skipped: 1},
+	}
+	if summary != want {
+		t.Error("Unexpected counts:", summary)
+	}
+
+	_, _, err = gotest.Parse(test, "/repo", dirs, []byte("go: cannot find main module\n"), true)
+	var toolErr *lint.ToolError
+	if !errors.As(err, &toolErr) {
+		t.Error("A failure without failing tests must be a tool error:", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module example.com/nanny\n\ngo 1.16\n",
+		"ok/ok.go":        "package ok\n",
+		"ok/ok_test.go":   "package ok\n\nimport \"testing\"\n\nfunc TestOK(t *testing.T) {}\n",
+		"bad/bad.go":      "package bad\n",
+		"bad/bad_test.go": "package bad\n\nimport \"testing\"\n\nfunc TestBad(t *testing.T) {\n\tt.Errorf(\"got %d\", 2)\n}\n",
+		"none/none.go":    "package none\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runner, err := lint.NewRunner(root, config.CodeNannyConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := loader.Load(context.Background(), root, []string{"./..."})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if results := gotest.Run(context.Background(), runner, program, ""); len(results) != 0 {
+		t.Error("The tests must only run when enabled:", results)
+	}
+	runner.Config.Enabled = map[string]bool{"test": true}
+	results := gotest.Run(context.Background(), runner, program, "")
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatal("Unexpected results:", results)
+	}
+	issues := results[0].Issues
+	if len(issues) != 1 || issues[0].String() != filepath.Join("bad", "bad_test.go")+":6: TestBad: got 2" {
+		t.Error("Unexpected issues:", issues)
+	}
+	want := lint.TestSummary{
+		Packages: lint.TestCounts{Passed: 1, Failed: 1, Skipped: 1},
+		Tests:    lint.TestCounts{Passed: 1, Failed: 1},
+	}
+	if results[0].Tests != want {
+		t.Error("Unexpected counts:", results[0].Tests)
+	}
+}
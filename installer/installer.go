@@ -25,42 +25,23 @@ import (
 	"strings"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/lagarciag/codenanny/linters"
 )
 
-//DisabledTool is used to track disabled tools.
-var DisabledTool map[string]bool
+//CheckExternalDependencies checks if the linters of registry that are enabled are installed, if not, it go gets them.
+//The returned map tracks the linters that could not be installed and must be disabled, err names them.
+func CheckExternalDependencies(registry *linters.Registry, enabled func(linters.Linter) bool) (disabledTool map[string]bool, err error) {
+	disabledTool = make(map[string]bool)
+	var failed []string
 
-var installMap = map[string]string{
-	"golint":      "github.com/golang/lint/golint",
-	"gotype":      "golang.org/x/tools/cmd/gotype",
-	"goimports":   "golang.org/x/tools/cmd/goimports",
-	"errcheck":    "github.com/kisielk/errcheck",
-	"varcheck":    "github.com/opennota/check/cmd/varcheck",
-	"structcheck": "github.com/opennota/check/cmd/structcheck",
-	"aligncheck":  "github.com/opennota/check/cmd/aligncheck",
-	"deadcode":    "github.com/tsenart/deadcode",
-	"gocyclo":     "github.com/alecthomas/gocyclo",
-	"misspell":    "github.com/client9/misspell/cmd/misspell",
-	"ineffassign": "github.com/gordonklaus/ineffassign",
-	"dupl":        "github.com/mibk/dupl",
-	"interfacer":  "github.com/mvdan/interfacer/cmd/interfacer",
-	"lll":         "github.com/walle/lll/cmd/lll",
-	"unconvert":   "github.com/mdempsky/unconvert",
-	"goconst":     "github.com/jgautheron/goconst/cmd/goconst",
-	"gosimple":    "honnef.co/go/simple/cmd/gosimple",
-	"staticcheck": "honnef.co/go/staticcheck/cmd/staticcheck",
-}
-
-//CheckExternalDependencies checks if a required component is installed, if not, it go gets it.
-func CheckExternalDependencies() (err error) {
-	DisabledTool = make(map[string]bool)
-
-	for key := range installMap {
-		packageToGet := installMap[key]
+	for _, linter := range registry.All() {
+		if linter.InstallPath == "" || !enabled(linter) {
+			continue
+		}
+		key := linter.Command
+		packageToGet := linter.InstallPath
 		//log.Debug("checking installation:", packageToGet)
-		_, err = exec.LookPath(key)
-
-		if err != nil {
+		if _, lookErr := exec.LookPath(key); lookErr != nil {
 			log.Debug("Need to install:", packageToGet)
 			var installErr error
 			for attempt := 0; attempt < 5; attempt++ {
@@ -75,7 +56,7 @@ func CheckExternalDependencies() (err error) {
 					for _, path := range splitGoPath {
 						log.Debug("PATH", path)
 						fullPackagePath := path + fmt.Sprintf("/%s", packageToGet)
-						if _, err = os.Stat(fullPackagePath); os.IsExist(err) {
+						if _, statErr := os.Stat(fullPackagePath); os.IsExist(statErr) {
 							rmErr := os.Remove(fullPackagePath)
 							log.Error("Could not remove:", rmErr)
 						}
@@ -91,17 +72,19 @@ func CheckExternalDependencies() (err error) {
 			}
 
 			if installErr != nil {
-				nErr := fmt.Errorf("Installation of %s did not work, returned:%s.  Disabling", packageToGet, err.Error())
+				nErr := fmt.Errorf("Installation of %s did not work, returned:%s.  Disabling", packageToGet, installErr.Error())
 				log.Error(nErr)
-				DisabledTool[key] = true
+				disabledTool[linter.Name] = true
+				failed = append(failed, linter.Name)
 			} else {
-				if _, err = exec.LookPath(key); err != nil {
-					nErr := fmt.Errorf("After installing %s, still can't find it:%s", key, err)
+				if _, lookErr := exec.LookPath(key); lookErr != nil {
+					nErr := fmt.Errorf("After installing %s, still can't find it:%s", key, lookErr)
 					log.Error(nErr.Error())
-					DisabledTool[key] = true
+					disabledTool[linter.Name] = true
+					failed = append(failed, linter.Name)
 				} else {
 					log.Debug("Package is good:", packageToGet)
-					DisabledTool[key] = false
+					disabledTool[linter.Name] = false
 
 				}
 
@@ -110,6 +93,24 @@ func CheckExternalDependencies() (err error) {
 		}
 		//log.Debug("Already installed:", key)
 	}
-	return err
+	if len(failed) > 0 {
+		err = fmt.Errorf("could not install %s", strings.Join(failed, ", "))
+	}
+	return disabledTool, err
 
 }
+
+//MissingLinters returns the linters of registry whose command is not found, without installing them
+func MissingLinters(registry *linters.Registry) (missing map[string]bool) {
+	missing = make(map[string]bool)
+	for _, linter := range registry.All() {
+		if linter.Analyzer != nil {
+			continue
+		}
+		if _, err := exec.LookPath(linter.Command); err != nil {
+			log.Debug("Not installed:", linter.Command)
+			missing[linter.Name] = true
+		}
+	}
+	return missing
+}
@@ -24,6 +24,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/lagarciag/codenanny/installer"
+	"github.com/lagarciag/codenanny/linters"
 )
 
 func TestMain(t *testing.M) {
@@ -36,7 +37,7 @@ func TestMain(t *testing.M) {
 
 func TestInstallerBasic(t *testing.T) {
 
-	if err := installer.CheckExternalDependencies(); err != nil {
+	if _, err := installer.CheckExternalDependencies(linters.Default, func(linter linters.Linter) bool { return linter.Enabled }); err != nil {
 		t.Error("Could not install package:", err)
 	}
 
@@ -0,0 +1,187 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package lint
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/lagarciag/codenanny/linters"
+)
+
+//FixTasks returns the tasks of tasks whose linter can fix the code, with the
+//linter FixArgs in place of its Args so running them rewrites the files
+func FixTasks(tasks []Task) (fixTasks []Task) {
+	for _, task := range tasks {
+		if !task.Linter.CanFix() {
+			continue
+		}
+		fixer := task.Linter
+		fixer.Args = fixer.FixArgs
+		fixTasks = append(fixTasks, Task{Linter: fixer, Targets: task.Targets})
+	}
+	return fixTasks
+}
+
+//PreviewFix runs the file fixer linter on src, the content of the file at path relative to the runner root,
+//and returns the fixed content. Nothing is written: the fixer reads src on its standard input and prints
+//the fixed content, as its PreviewArgs tell it to.
+func (r *Runner) PreviewFix(ctx context.Context, linter linters.Linter, path string, src []byte) (fixed []byte, err error) {
+	settings := make(map[string]string)
+	for key, value := range r.Config.Settings[linter.Name] {
+		settings[key] = value
+	}
+	settings["file"] = filepath.Join(r.Root, path)
+	args, err := linter.ExpandArgs(linter.PreviewArgs, settings)
+	if err != nil {
+		return nil, err
+	}
+	out, stdout, failed, err := r.run(ctx, linter, args, src)
+	if err != nil {
+		return nil, err
+	}
+	if failed {
+		return nil, &ToolError{Linter: linter.Name, Reason: "could not preview the fixes of " + path, Output: string(out)}
+	}
+	return stdout, nil
+}
+
+//ApplyFixes applies the first suggested fix of each issue to the files below root.
+//Fixes overlapping a fix already applied are skipped, a fix suggested by several
+//issues is applied once. It returns the files changed, relative to root and sorted.
+func ApplyFixes(root string, issues []Issue) (changed []string, err error) {
+	for path, edits := range fixEdits(issues) {
+		if err = applyEdits(filepath.Join(root, path), edits); err != nil {
+			return changed, err
+		}
+		changed = append(changed, path)
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+//FixContents is ApplyFixes on contents, the content of the files by path relative to root, which it updates.
+//The files missing from contents are read below root. Nothing is written.
+func FixContents(root string, contents map[string][]byte, issues []Issue) (changed []string, err error) {
+	for path, edits := range fixEdits(issues) {
+		content, found := contents[path]
+		if !found {
+			if content, err = ioutil.ReadFile(filepath.Join(root, path)); err != nil {
+				return changed, err
+			}
+		}
+		if contents[path], err = edit(path, content, edits); err != nil {
+			return changed, err
+		}
+		changed = append(changed, path)
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+//fixEdits returns the edits of the first suggested fix of each issue by path,
+//leaving out the fixes overlapping a fix already accepted
+func fixEdits(issues []Issue) (byPath map[string][]Edit) {
+	byPath = make(map[string][]Edit)
+	accepted := make(map[string][]Edit)
+	for _, issue := range issues {
+		if len(issue.Fixes) == 0 {
+			continue
+		}
+		edits := issue.Fixes[0].Edits
+		if conflicts(accepted, edits) {
+			continue
+		}
+		for _, edit := range edits {
+			if !hasEdit(accepted[edit.Path], edit) {
+				accepted[edit.Path] = append(accepted[edit.Path], edit)
+				byPath[edit.Path] = append(byPath[edit.Path], edit)
+			}
+		}
+	}
+	return byPath
+}
+
+//conflicts tells if one of edits overlaps an edit of accepted without being the same edit
+func conflicts(accepted map[string][]Edit, edits []Edit) bool {
+	for _, edit := range edits {
+		for _, other := range accepted[edit.Path] {
+			if edit == other {
+				continue
+			}
+			if edit.Start < other.End && other.Start < edit.End ||
+				edit.Start == other.Start && edit.End == other.End {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//hasEdit tells if edit is in edits
+func hasEdit(edits []Edit, edit Edit) bool {
+	for _, other := range edits {
+		if other == edit {
+			return true
+		}
+	}
+	return false
+}
+
+//applyEdits rewrites the file in path with edits, which must not overlap
+func applyEdits(path string, edits []Edit) (err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	fixed, err := edit(path, content, edits)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, fixed, info.Mode())
+}
+
+//edit returns content, the content of the file at path, with edits applied
+func edit(path string, content []byte, edits []Edit) (fixed []byte, err error) {
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].Start != edits[j].Start {
+			return edits[i].Start < edits[j].Start
+		}
+		return edits[i].End < edits[j].End
+	})
+
+	last := 0
+	for _, change := range edits {
+		if change.Start < last || change.End < change.Start || change.End > len(content) {
+			return nil, fmt.Errorf("invalid edit of %s at offsets %d-%d", path, change.Start, change.End)
+		}
+		fixed = append(fixed, content[last:change.Start]...)
+		fixed = append(fixed, change.NewText...)
+		last = change.End
+	}
+	fixed = append(fixed, content[last:]...)
+	return fixed, nil
+}
@@ -0,0 +1,296 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lagarciag/codenanny/linters"
+)
+
+//Severity tells how serious an issue is
+type Severity string
+
+const (
+	//SeverityError issues make the run fail
+	SeverityError Severity = "error"
+	//SeverityWarning issues are reported but do not make the run fail
+	SeverityWarning Severity = "warning"
+	//SeverityInfo issues are reported for information only
+	SeverityInfo Severity = "info"
+)
+
+//severityRank orders the severities, unknown ones are errors
+var severityRank = map[Severity]int{
+	SeverityInfo:    1,
+	SeverityWarning: 2,
+	SeverityError:   3,
+}
+
+//ParseSeverity returns the severity named level
+func ParseSeverity(level string) (severity Severity, err error) {
+	severity = Severity(level)
+	if _, found := severityRank[severity]; !found {
+		return severity, fmt.Errorf("unknown severity %q, use info, warning or error", level)
+	}
+	return severity, nil
+}
+
+//AtLeast tells if s is as serious as other or more
+func (s Severity) AtLeast(other Severity) bool {
+	return s.rank() >= other.rank()
+}
+
+func (s Severity) rank() int {
+	if rank, found := severityRank[s]; found {
+		return rank
+	}
+	return severityRank[SeverityError]
+}
+
+//Issue is a single finding reported by a linter
+type Issue struct {
+	Linter   string
+	Path     string
+	Line     int
+	Col      int
+	Message  string
+	Severity Severity
+	//Category is the rule or check code reported by the linter, if any
+	Category string
+	//Linters are the linters that reported the same issue, Linter first, when it was merged from duplicates
+	Linters []string
+	//Fixes are the changes suggested by the linter, each one solves the issue on its own
+	Fixes []Fix
+}
+
+//Fix is a change suggested to solve an issue
+type Fix struct {
+	Message string
+	Edits   []Edit
+}
+
+//Edit replaces the bytes of Path between the Start and End offsets with NewText
+type Edit struct {
+	Path    string
+	Start   int
+	End     int
+	NewText string
+}
+
+//String formats the issue the way most linters print it: path:line:col: message
+func (i Issue) String() string {
+	var parts []string
+	if i.Path != "" {
+		parts = append(parts, i.Path)
+	}
+	if i.Line > 0 {
+		parts = append(parts, strconv.Itoa(i.Line))
+	}
+	if i.Col > 0 {
+		parts = append(parts, strconv.Itoa(i.Col))
+	}
+	if len(parts) == 0 {
+		return i.Message
+	}
+	location := strings.Join(parts, ":")
+	if i.Message == "" {
+		return location
+	}
+	return fmt.Sprintf("%s: %s", location, i.Message)
+}
+
+//Reporters returns the linters that reported the issue, Linter first
+func (i Issue) Reporters() []string {
+	if len(i.Linters) == 0 {
+		return []string{i.Linter}
+	}
+	return i.Linters
+}
+
+//ParseOutput turns the output of linter into issues using the linter pattern.
+//When the pattern matches nothing and failed is set the output is not made of
+//findings, it is returned in a *ToolError so a failing linter never passes silently.
+func ParseOutput(linter linters.Linter, out []byte, failed bool) (issues []Issue, err error) {
+	re, err := linter.Regexp()
+	if err != nil {
+		return issues, err
+	}
+	names := re.SubexpNames()
+	for _, match := range re.FindAllSubmatch(out, -1) {
+		issue := Issue{Linter: linter.Name, Severity: SeverityError}
+		for id, name := range names {
+			value := strings.TrimSpace(string(match[id]))
+			switch name {
+			case "path":
+				issue.Path = filepath.Clean(value)
+			case "line":
+				issue.Line, _ = strconv.Atoi(value)
+			case "col":
+				issue.Col, _ = strconv.Atoi(value)
+			case "message":
+				issue.Message = value
+			case "category":
+				issue.Category = value
+			}
+		}
+		if issue.Message == "" && issue.Path == "" {
+			issue.Message = strings.TrimSpace(string(match[0]))
+		}
+		//Without a category group, the check code the message ends with is the category
+		if code := checkCode.FindStringSubmatch(issue.Message); issue.Category == "" && code != nil {
+			issue.Category = code[1]
+		}
+		issues = append(issues, issue)
+	}
+
+	if len(issues) == 0 && failed {
+		return nil, &ToolError{Linter: linter.Name, Reason: "failed and no issue matched its output", Output: string(out)}
+	}
+	return issues, nil
+}
+
+//SortIssues orders issues by path, line, column, linter and message
+func SortIssues(issues []Issue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		switch {
+		case a.Path != b.Path:
+			return a.Path < b.Path
+		case a.Line != b.Line:
+			return a.Line < b.Line
+		case a.Col != b.Col:
+			return a.Col < b.Col
+		case a.Linter != b.Linter:
+			return a.Linter < b.Linter
+		}
+		return a.Message < b.Message
+	})
+}
+
+//checkCode matches the check codes some linters append to their messages, in parentheses like (SA4006).
+//The parentheses are required, messages may end with words like ISO8601.
+var checkCode = regexp.MustCompile(`(?:^|\s+)\(([A-Z]{1,4}\d{3,4})\)\s*$`)
+
+//nonWords matches what NormalizeMessage drops from messages
+var nonWords = regexp.MustCompile(`[^a-z0-9]+`)
+
+//NormalizeMessage reduces a message to lower case words, without check code nor punctuation
+func NormalizeMessage(message string) string {
+	message = checkCode.ReplaceAllString(message, "")
+	return strings.TrimSpace(nonWords.ReplaceAllString(strings.ToLower(message), " "))
+}
+
+//Dedupe merges the issues reported by several linters at the same line. Issues
+//are the same when they have the same category, or the same normalized message.
+//The merged issue is the one of the linter found first in precedence, linters not
+//listed there come after the listed ones, by name. Its Linters tell who agreed on
+//it and it gets the highest severity of the merged issues.
+func Dedupe(issues []Issue, precedence []string) (deduped []Issue) {
+	rank := make(map[string]int, len(precedence))
+	for id, name := range precedence {
+		rank[name] = id + 1
+	}
+	before := func(a, b string) bool {
+		ra, rb := rank[a], rank[b]
+		switch {
+		case ra == rb:
+			return a < b
+		case ra == 0:
+			return false
+		case rb == 0:
+			return true
+		}
+		return ra < rb
+	}
+
+	type location struct {
+		path string
+		line int
+	}
+	byLocation := make(map[location][]int)
+	for _, issue := range issues {
+		loc := location{issue.Path, issue.Line}
+		merged := false
+		for _, id := range byLocation[loc] {
+			kept := &deduped[id]
+			if !sameIssue(*kept, issue) {
+				continue
+			}
+			linters := append(kept.Reporters(), issue.Reporters()...)
+			severity := kept.Severity
+			if issue.Severity.AtLeast(severity) {
+				severity = issue.Severity
+			}
+			if before(issue.Linter, kept.Linter) {
+				if len(issue.Fixes) == 0 {
+					issue.Fixes = kept.Fixes
+				}
+				*kept = issue
+			} else if len(kept.Fixes) == 0 {
+				kept.Fixes = issue.Fixes
+			}
+			kept.Linters = orderLinters(kept.Linter, linters, before)
+			kept.Severity = severity
+			merged = true
+			break
+		}
+		if !merged {
+			byLocation[loc] = append(byLocation[loc], len(deduped))
+			deduped = append(deduped, issue)
+		}
+	}
+	return deduped
+}
+
+//sameIssue tells if two issues at the same line, reported by different linters, report the same problem.
+//The findings of a linter are never merged together, however alike they are.
+func sameIssue(a, b Issue) bool {
+	if a.Path == "" || a.Line == 0 {
+		return false
+	}
+	for _, name := range a.Reporters() {
+		for _, other := range b.Reporters() {
+			if name == other {
+				return false
+			}
+		}
+	}
+	if a.Category != "" && a.Category == b.Category {
+		return true
+	}
+	return NormalizeMessage(a.Message) == NormalizeMessage(b.Message)
+}
+
+//orderLinters returns the unique linters, first then the others by precedence
+func orderLinters(first string, linters []string, before func(a, b string) bool) (ordered []string) {
+	seen := map[string]bool{first: true}
+	for _, name := range linters {
+		if !seen[name] {
+			seen[name] = true
+			ordered = append(ordered, name)
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool { return before(ordered[i], ordered[j]) })
+	return append([]string{first}, ordered...)
+}
@@ -20,435 +20,314 @@ package lint
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"os"
-	"os/exec"
-	"strings"
-
-	"bufio"
-	"container/list"
 	"io"
+	"os/exec"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
 
-	log "github.com/sirupsen/logrus"
 	"github.com/lagarciag/codenanny/config"
-	"github.com/lagarciag/codenanny/installer"
+	"github.com/lagarciag/codenanny/linters"
+	log "github.com/sirupsen/logrus"
 )
 
-var lintersFlag = map[string]string{
-	"aligncheck":  `aligncheck .:^(?:[^:]+: )?(?P<path>[^:]+):(?P<line>\d+):(?P<col>\d+):\s*(?P<message>.+)$`,
-	"deadcode":    `deadcode  `,
-	"dupl":        `dupl -plumbing -threshold {duplthreshold} ./*.go:^(?P<path>[^\s][^:]+?\.go):(?P<line>\d+)-\d+:\s*(?P<message>.*)$`,
-	"errcheck":    `errcheck `,
-	"goconst":     `goconst`,
-	"gocyclo":     `gocyclo -over {mincyclo} .:^(?P<cyclo>\d+)\s+\S+\s(?P<function>\S+)\s+(?P<path>[^:]+):(?P<line>\d+):(\d+)$`,
-	"gofmt":       `gofmt -l -s ./*.go:^(?P<path>[^\n]+)$`,
-	"goimports":   `goimports -w`,
-	"golint":      "golint -set_exit_status ",
-	"gotype":      "gotype -e -a ",
-	"ineffassign": `ineffassign -n .:PATH:LINE:COL:MESSAGE`,
-	"interfacer":  `interfacer `,
-	"lll":         `lll -g -l {maxlinelength} ./*.go:PATH:LINE:MESSAGE`,
-	"structcheck": `structcheck {tests=-t} .:^(?:[^:]+: )?(?P<path>[^:]+):(?P<line>\d+):(?P<col>\d+):\s*(?P<message>.+)$`,
-	"test":        `go test:^--- FAIL: .*$\s+(?P<path>[^:]+):(?P<line>\d+): (?P<message>.*)$`,
-	"testify":     `go test:Location:\s+(?P<path>[^:]+):(?P<line>\d+)$\s+Error:\s+(?P<message>[^\n]+)`,
-	"varcheck":    `varcheck .:^(?:[^:]+: )?(?P<path>[^:]+):(?P<line>\d+):(?P<col>\d+):[\s\t]+(?P<message>.*)$`,
-	"vet":         `go vet`,
-	"vetshadow":   `go tool vet -shadow=true`,
-	"unconvert":   "unconvert -apply",
-	"gosimple":    "gosimple ",
-	"staticcheck": "staticcheck ",
-	"misspell":    "misspell ./*.go:PATH:LINE:COL:MESSAGE",
+//Runner runs the linters of a repo. It carries all the state of a run, so
+//several runners can work at the same time in the same process.
+type Runner struct {
+	//Root is the directory the linters run in, usually the git repo root
+	Root string
+	//Config is the .codenanny configuration of the repo
+	Config config.CodeNannyConfig
+	//Registry holds the builtin and custom linters known to the run
+	Registry *linters.Registry
+	//Disabled tracks the linters that could not be installed
+	Disabled map[string]bool
+	//Jobs is the number of linters run at the same time
+	Jobs int
+	//Timeout is the time a linter is allowed to run when its settings do not say otherwise, zero means forever
+	Timeout time.Duration
 }
 
-var singlePackageLinters = []string{
-	"golint",
-	//	"unconvert",
-	//	"staticcheck",
-	//	"interfacer",
+//NewRunner returns a runner for the repo in root configured by conf
+func NewRunner(root string, conf config.CodeNannyConfig) (r *Runner, err error) {
+	registry, err := conf.Registry()
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := conf.GlobalTimeout()
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{
+		Root:     root,
+		Config:   conf,
+		Registry: registry,
+		Disabled: make(map[string]bool),
+		Jobs:     runtime.GOMAXPROCS(0),
+		Timeout:  timeout,
+	}, nil
 }
 
-var multiPapackageLinter = []string{
-	"errcheck",
-	"vet",
-	"gosimple",
+//EnabledLinters returns the linters of a scope that are enabled and installed
+func (r *Runner) EnabledLinters(scope linters.Scope) (list []linters.Linter) {
+	for _, linter := range r.Registry.ByScope(scope) {
+		if !r.IsEnabled(linter) {
+			continue
+		}
+		if r.Disabled[linter.Name] {
+			log.Warn("Could not run missing tool:", linter.Name)
+			continue
+		}
+		list = append(list, linter)
+	}
+	return list
 }
 
-var dirMultiLinters = []string{
-	//"goimports",
-	"goconst",
+//IsEnabled tells if a linter should run, .codenanny disabled entries have precedence
+func (r *Runner) IsEnabled(linter linters.Linter) bool {
+	if r.Config.Disabled[linter.Name] {
+		return false
+	}
+	if enabled, found := r.Config.Enabled[linter.Name]; found {
+		return enabled
+	}
+	return linter.Enabled
 }
 
-var dirRecurseiveLinters = []string{
-	//"goimports",
-	//"goconst",
-	"vetshadow",
+//TimeoutError is returned when a linter does not finish in time
+type TimeoutError struct {
+	Linter  string
+	Timeout time.Duration
 }
 
-//CheckMultiPackages runs linters and code checkers in the passed list of packages
-func CheckMultiPackages(listOfPackages []string) (err error) {
-	//Find out what the Root Path is
-	var tmpErr error
-	var errCount int
-	log.Debug("Checking pakages...", listOfPackages)
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	tmpRootPath, err := cmd.Output()
-	if err != nil {
-		return err
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.Linter, e.Timeout)
+}
+
+//ToolError is returned when a linter did not do its job: it could not be run,
+//crashed, or failed with an output that is not made of issues.
+//Tool errors are never findings, Output holds what the linter printed.
+type ToolError struct {
+	Linter string
+	//Reason tells what went wrong
+	Reason string
+	//Output is the raw output of the linter, stdout and stderr together
+	Output string
+	Err    error
+}
+
+func (e *ToolError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s %s:%s", e.Linter, e.Reason, e.Err.Error())
+	}
+	return fmt.Sprintf("%s %s", e.Linter, e.Reason)
+}
+
+func (e *ToolError) Unwrap() error {
+	return e.Err
+}
+
+//goPanic matches the start of the report a Go program prints on stderr when it panics
+var goPanic = regexp.MustCompile(`(?m)^(panic: |fatal error: |goroutine \d+ \[running\]:)`)
+
+//linterTimeout returns the time linter is allowed to run, zero meaning forever.
+//The timeout key of the linter settings has precedence over the linter
+//declaration, which has precedence over the runner Timeout.
+func (r *Runner) linterTimeout(linter linters.Linter) (timeout time.Duration, err error) {
+	if value := r.Config.Settings[linter.Name]["timeout"]; value != "" {
+		if timeout, err = time.ParseDuration(value); err != nil {
+			return 0, fmt.Errorf("invalid timeout for %s:%s", linter.Name, err.Error())
+		}
+		return timeout, nil
 	}
+	if linter.Timeout > 0 {
+		return linter.Timeout, nil
+	}
+	return r.Timeout, nil
+}
 
-	//Trim return character
-	rootPath := strings.TrimSpace(string(tmpRootPath))
-	err = os.Chdir(rootPath)
+//RunLinter executes linter in the runner root with targets appended to its arguments.
+//failed is set when the linter exits with an error, err when it times out, ctx is
+//cancelled, or it can't be started or crashes, which is a *ToolError.
+func (r *Runner) RunLinter(ctx context.Context, linter linters.Linter, targets []string) (out []byte, failed bool, err error) {
+	args, err := linter.ExpandArgs(linter.Args, r.Config.Settings[linter.Name])
 	if err != nil {
-		return err
+		return out, failed, err
 	}
-	log.Debug("Root path is:", rootPath)
-
-	for _, linter := range multiPapackageLinter {
-		log.Debug("Running linter:", linter)
-		if !installer.DisabledTool[linter] {
-			log.Debug("Running package checker:", linter)
-			cmdString := lintersFlag[linter]
-			splitCmd := strings.Split(cmdString, " ")
-
-			cmdList := list.New()
-
-			//Create a linked list of the original command
-			for _, aCmd := range splitCmd {
-				cmdList.PushBack(aCmd)
-			}
-			//Add packages to the linked list
-			for _, aPackage := range listOfPackages {
-
-				cmdList.PushBack(aPackage)
-			}
-
-			//Convert the linked list into a string
-			argsList := make([]string, cmdList.Len())
-			count := 0
-			for e := cmdList.Front(); e != nil; e = e.Next() {
-				argsList[count] = e.Value.(string)
-				count++
-
-			}
-
-			msg := fmt.Sprintf("LINTER CMD: %s %v", splitCmd[0], argsList)
-			log.Debug(msg)
-			cmd := exec.Command(splitCmd[0], splitCmd[1])
-			cmd.Args = argsList
-
-			out, errOut := cmd.CombinedOutput()
-			if errOut != nil {
-				//Check patterns here
-				errList, _ := readErrorsFromChecker(out, linter)
-				if len(errList) > 0 {
-					errCount++
-					tmpErr = fmt.Errorf("%s found errors", linter)
-					log.Error(tmpErr)
-				} else {
-					log.Warnf("%s found Errors but an error ingnore matched", linter)
-					err = nil
-				}
-			}
-		} else {
-			log.Warn("Could not run disabled tool:", linter)
-		}
+	args = append(args, targets...)
+	out, _, failed, err = r.run(ctx, linter, args, nil)
+	return out, failed, err
+}
 
+//run executes linter in the runner root with args, feeding it stdin when set. out is what it
+//printed on both its outputs, stdout what it printed on its standard output. See RunLinter.
+func (r *Runner) run(ctx context.Context, linter linters.Linter, args []string, stdin []byte) (out, stdout []byte, failed bool, err error) {
+	timeout, err := r.linterTimeout(linter)
+	if err != nil {
+		return out, stdout, failed, err
+	}
+	runCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	if tmpErr != nil {
-		err = fmt.Errorf("Found %d package linter errors", errCount)
+	log.Debugf("LINTER CMD: %s %v", linter.Command, args)
+	cmd := exec.CommandContext(runCtx, linter.Command, args...)
+	cmd.Dir = r.Root
+	setProcessGroup(cmd)
+	cmd.WaitDelay = time.Second
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	//stdout and stderr are also kept apart, to look for panics
+	combined := &syncBuffer{}
+	var stdoutBuf, stderr bytes.Buffer
+	cmd.Stdout = io.MultiWriter(combined, &stdoutBuf)
+	cmd.Stderr = io.MultiWriter(combined, &stderr)
+	errOut := cmd.Run()
+	out, stdout = combined.Bytes(), stdoutBuf.Bytes()
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		return out, stdout, true, ctx.Err()
+	case runCtx.Err() == context.DeadlineExceeded:
+		return out, stdout, true, &TimeoutError{Linter: linter.Name, Timeout: timeout}
+	case errOut != nil && !errors.As(errOut, &exitErr):
+		return out, stdout, true, &ToolError{Linter: linter.Name, Reason: "could not be run", Output: string(out), Err: errOut}
+	case goPanic.Match(stderr.Bytes()):
+		return out, stdout, true, &ToolError{Linter: linter.Name, Reason: "panicked", Output: string(out)}
+	case exitErr != nil && exitErr.ExitCode() < 0:
+		return out, stdout, true, &ToolError{Linter: linter.Name, Reason: "crashed", Output: string(out), Err: errOut}
 	}
-	return err
+	return out, stdout, errOut != nil, nil
+}
+
+//syncBuffer is a buffer the stdout and stderr copiers of a command can write at the same time
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (n int, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Bytes()
+}
+
+//CheckFiles runs the file linters once on the passed list of files
+func (r *Runner) CheckFiles(ctx context.Context, listOfFiles []string) (issues []Issue, err error) {
+	log.Debug("Checking files...", listOfFiles)
+	return collect(r.Execute(ctx, r.FileTasks(listOfFiles)))
+}
+
+//CheckMultiPackages runs linters and code checkers in the passed list of packages
+func (r *Runner) CheckMultiPackages(ctx context.Context, listOfPackages []string) (issues []Issue, err error) {
+	log.Debug("Checking pakages...", listOfPackages)
+	return collect(r.Execute(ctx, r.MultiPackageTasks(listOfPackages)))
 }
 
 //CheckSinglePackages runs linters and code checkers in the passed list of packages
-func CheckSinglePackages(listOfPackages []string) (err error) {
-	//Find out what the Root Path is
-	var tmpErr error
-	var errCount int
+func (r *Runner) CheckSinglePackages(ctx context.Context, listOfPackages []string) (issues []Issue, err error) {
 	log.Debug("Checking pakages...", listOfPackages)
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	tmpRootPath, err := cmd.Output()
-	if err != nil {
-		return err
-	}
+	return collect(r.Execute(ctx, r.SinglePackageTasks(listOfPackages)))
+}
 
-	//Trim return character
-	rootPath := strings.TrimSpace(string(tmpRootPath))
-	err = os.Chdir(rootPath)
+//readIssuesFromChecker parses the linter output and drops the issues matching an ignore pattern
+func (r *Runner) readIssuesFromChecker(cherrs []byte, linter linters.Linter, failed bool) (retList []Issue, err error) {
+	issues, err := ParseOutput(linter, cherrs, failed)
 	if err != nil {
-		return err
-	}
-	log.Debug("Root path is:", rootPath)
-	for _, aPackage := range listOfPackages {
-		log.Debug("Checking package:", aPackage)
-		for _, linter := range singlePackageLinters {
-			if !installer.DisabledTool[linter] {
-				log.Debug("Running package checker:", linter)
-				cmdString := lintersFlag[linter]
-				splitCmd := strings.Split(cmdString, " ")
-				msg := fmt.Sprintf("CMD: %s %s %s", splitCmd[0], splitCmd[1], aPackage)
-				log.Debug(msg)
-				cmd := exec.Command(splitCmd[0], splitCmd[1], aPackage)
-				out, errOut := cmd.CombinedOutput()
-				if errOut != nil {
-					//Check patterns here
-					errList, _ := readErrorsFromChecker(out, linter)
-					if len(errList) > 0 {
-						errCount++
-						tmpErr = fmt.Errorf("%s found errors", linter)
-						log.Error(tmpErr)
-					} else {
-						log.Warnf("%s found Errors but an error ingnore matched", linter)
-						err = nil
-					}
-				}
-			} else {
-				log.Warn("Could not run disabled tool:", linter)
-			}
-		}
-	}
-	if tmpErr != nil {
-		err = fmt.Errorf("Found %d package linter errors", errCount)
+		return retList, err
 	}
-	return err
+	return r.Ignore(linter.Name, issues)
 }
 
-func readErrorsFromChecker(cherrs []byte, tool string) (retList []string, err error) {
-	var errEOF error
-	var aLine string
-	var pattern string
-	var match = false
-	patterns := config.GlobalConfig.IgnorePattern
+//Ignore drops the issues of tool matching one of its .codenanny ignore patterns
+func (r *Runner) Ignore(tool string, issues []Issue) (retList []Issue, err error) {
+	var ignore *regexp.Regexp
+	patterns := r.Config.IgnorePattern
 
-	listOfPatterns, foundPattern := patterns[tool]
-	log.Debug("PATTERNS", listOfPatterns)
-	if foundPattern {
-		//log.Info("Found patterns for tool:", tool)
-		//log.Info("Found patterns for tool:", listOfPatterns)
-		for patID, pat := range listOfPatterns {
-			if patID == 0 {
-				pattern = pat
-			} else {
-				pattern = fmt.Sprintf("%s|%s", pattern, pat)
-			}
+	//Patterns with an expiry date are applied once every linter ran, by the suppress package
+	var listOfPatterns []string
+	for _, pattern := range patterns[tool] {
+		if pattern.Until == "" {
+			listOfPatterns = append(listOfPatterns, pattern.Pattern)
 		}
+	}
+	log.Debug("PATTERNS", listOfPatterns)
+	if len(listOfPatterns) > 0 {
+		pattern := strings.Join(listOfPatterns, "|")
 		log.Debug("Pattern to exclude:", pattern)
+		if ignore, err = regexp.Compile(pattern); err != nil {
+			return retList, fmt.Errorf("invalid ignore pattern for %s:%s", tool, err.Error())
+		}
 	}
 
-	errList := list.New()
-	aReader := bytes.NewReader(cherrs)
-	r1 := bufio.NewReader(aReader)
-	for errEOF != io.EOF {
-		aLine, errEOF = r1.ReadString(10) //  line was defined before
-		aLine = strings.Trim(aLine, "\n")
-		if foundPattern {
-			match, _ = regexp.MatchString(pattern, aLine)
-			if match {
-				log.Debug("------>>>> MATCH:", aLine)
-				log.Debug("------>>>> PATTERN:", pattern)
-			}
-		}
-		if !match && aLine != "" {
-			errList.PushBack(aLine)
-			log.Errorf("%s:%s", tool, aLine)
-		} else {
-			if aLine != "" {
-				log.Warnf("%s:%s", tool, aLine)
-			}
+	for _, issue := range issues {
+		if ignore != nil && ignore.MatchString(issue.String()) {
+			log.Debug("------>>>> MATCH:", issue)
+			log.Warnf("%s:%s", tool, issue)
+			continue
 		}
-		match = false
+		log.Errorf("%s:%s", tool, issue)
+		retList = append(retList, issue)
 	}
-	retList = make([]string, errList.Len())
-	count := 0
-	for e := errList.Front(); e != nil; e = e.Next() {
-		retList[count] = e.Value.(string)
-		count++
+	if len(retList) == 0 && len(issues) > 0 {
+		log.Warnf("%s found Errors but an error ingnore matched", tool)
 	}
-	return retList, err
+	return retList, nil
 }
 
-//CheckMultiDirs runs linters and checkers on directories provided in listOfDirs
-func CheckMultiDirs(listOfDirs []string) (err error) {
-	var errCount int
-	var tmpErr error
-
-	//------------------------------
-	//change dir into git root path
-	//------------------------------
-	//if err = chgDirToGitRootPath();err != nil {
-	//	return err
-	//}
-
-	//------------------------------
-	// Iterate through each passed
-	// directory
-	//------------------------------
-	for _, aDir := range listOfDirs {
-		log.Debug("Checking dir:", aDir)
-		//---------------------------------
-		// To each directory run a checker
-		//---------------------------------
-		for _, checker := range dirMultiLinters {
-			if !installer.DisabledTool[checker] {
-				var lCmd *exec.Cmd
-				log.Debug("Running dir checker:", checker)
-				cmdString := lintersFlag[checker]
-				splitCmd := strings.Split(cmdString, " ")
-				switch {
-				case len(splitCmd) == 1:
-					lCmd = exec.Command(splitCmd[0], aDir)
-				case len(splitCmd) == 2:
-					lCmd = exec.Command(splitCmd[0], splitCmd[1], aDir)
-				case len(splitCmd) == 3:
-					lCmd = exec.Command(splitCmd[0], splitCmd[1], splitCmd[2], aDir)
-				case len(splitCmd) == 4:
-					lCmd = exec.Command(splitCmd[0], splitCmd[1], splitCmd[2], splitCmd[3], aDir)
-				case len(splitCmd) == 5:
-					lCmd = exec.Command(splitCmd[0], splitCmd[1], splitCmd[2], splitCmd[3], splitCmd[4], aDir)
-				case len(splitCmd) == 0 || len(splitCmd) > 5:
-					log.Error("This command is not correctly handled", splitCmd[0])
-					log.Error("LENTH:", len(splitCmd))
-					log.Fatal("Handle this error")
-				}
-
-				//---------------------------------------------
-				//                Execute command
-				//---------------------------------------------
-				out, errOut := lCmd.CombinedOutput()
-
-				//---------------------------------
-				// Handle errors, if there are any
-				//---------------------------------
-				if errOut != nil {
-					//-------------------------------------
-					// Iterate through erros and verify
-					// if any has an exclusion
-					//-------------------------------------
-
-					errList, _ := readErrorsFromChecker(out, checker)
-					if len(errList) > 0 {
-						errCount++
-						tmpErr = fmt.Errorf("%s found errors", checker)
-						log.Error(tmpErr)
-					} else {
-						log.Warnf("%s found Errors but an error ingnore matched", checker)
-						err = nil
-					}
-				}
-
-			} else {
-				log.Warn("Could not run disabled tool:", checker)
-			}
-
+//ApplySeverity sets the severity configured in .codenanny for each issue, by linter and category
+func (r *Runner) ApplySeverity(issues []Issue) {
+	for id := range issues {
+		if level := r.Config.SeverityOf(issues[id].Linter, issues[id].Category); level != "" {
+			issues[id].Severity = Severity(level)
 		}
-
 	}
-	if tmpErr != nil {
-		err = fmt.Errorf("Found %d package linter errors", errCount)
-	}
-	return err
 }
 
-//CheckRecursiveDirs runs linters and checkers on directories provided in listOfDirs
-func CheckRecursiveDirs(listOfDirs []string) (err error) {
-	var errCount int
-	var tmpErr error
-	var theDir string
+//CheckMultiDirs runs linters and checkers on directories provided in listOfDirs
+func (r *Runner) CheckMultiDirs(ctx context.Context, listOfDirs []string) (issues []Issue, err error) {
+	log.Debug("Checking dirs:", listOfDirs)
+	return collect(r.Execute(ctx, r.DirTasks(listOfDirs)))
+}
 
+//CheckRecursiveDirs runs linters and checkers on directories provided in listOfDirs
+func (r *Runner) CheckRecursiveDirs(ctx context.Context, listOfDirs []string) (issues []Issue, err error) {
 	log.Debug("List of Dirs:", listOfDirs)
+	return collect(r.Execute(ctx, r.RecursiveTasks(listOfDirs)))
+}
 
-	if len(listOfDirs) == 0 {
-		log.Warn("CheckRecursiveDir List is empty")
-		return nil
-	}
-
-	if len(listOfDirs) == 1 {
-		theDir = listOfDirs[0]
-	} else {
-		theDir = "./"
-	}
-
-	//---------------------------------
-	// To each directory run a checker
-	//---------------------------------
-	for _, checker := range dirRecurseiveLinters {
-		if !installer.DisabledTool[checker] {
-			var lCmd *exec.Cmd
-			log.Debug("Running dir checker:", checker)
-			cmdString := lintersFlag[checker]
-			splitCmd := strings.Split(cmdString, " ")
-			switch {
-			case len(splitCmd) == 1:
-				lCmd = exec.Command(splitCmd[0], theDir)
-			case len(splitCmd) == 2:
-				lCmd = exec.Command(splitCmd[0], splitCmd[1], theDir)
-			case len(splitCmd) == 3:
-				lCmd = exec.Command(splitCmd[0], splitCmd[1], splitCmd[2], theDir)
-			case len(splitCmd) == 4:
-				lCmd = exec.Command(splitCmd[0], splitCmd[1], splitCmd[2], splitCmd[3], theDir)
-			case len(splitCmd) == 5:
-				lCmd = exec.Command(splitCmd[0], splitCmd[1], splitCmd[2], splitCmd[3], splitCmd[4], theDir)
-			case len(splitCmd) == 0 || len(splitCmd) > 5:
-				log.Error("This command is not correctly handled", splitCmd[0])
-				log.Error("LENTH:", len(splitCmd))
-				log.Fatal("Handle this error")
-			}
-
-			//---------------------------------------------
-			//                Execute command
-			//---------------------------------------------
-			out, errOut := lCmd.CombinedOutput()
-
-			//---------------------------------
-			// Handle errors, if there are any
-			//---------------------------------
-			if errOut != nil {
-				//-------------------------------------
-				// Iterate through erros and verify
-				// if any has an exclusion
-				//-------------------------------------
-				//log.Info("Errors found for checker:",checker)
-				errList, _ := readErrorsFromChecker(out, checker)
-				if len(errList) > 0 {
-					errCount++
-					tmpErr = fmt.Errorf("%s found errors", checker)
-					log.Error(tmpErr)
-				} else {
-					log.Warnf("%s found Errors but an error ingnore matched", checker)
-					err = nil
-				}
-			}
-
-		} else {
-			log.Warn("Could not run disabled tool:", checker)
-		}
-
+//dirTarget makes relative directories explicit, so they are not taken for import paths
+func dirTarget(dir string) string {
+	if filepath.IsAbs(dir) || strings.HasPrefix(dir, ".") {
+		return dir
 	}
-	if tmpErr != nil {
-		err = fmt.Errorf("Found %d package linter errors", errCount)
-	}
-	return err
+	return "." + string(filepath.Separator) + dir
 }
 
-//ChgDirToGitRootPath chages current working dir to gits repo root
-func ChgDirToGitRootPath() (err error) {
-
-	//-------------------------------------
-	// Detect the git root path
-	//-------------------------------------
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	tmpRootPath, err := cmd.Output()
-	if err != nil {
-		return err
+//RelPath returns path relative to root, or as is when it is relative or not below root.
+//Issues are reported at paths relative to the runner root.
+func RelPath(root, path string) string {
+	if !filepath.IsAbs(path) {
+		return path
 	}
-
-	//-------------------------------------
-	// cd into the git root path
-	//-------------------------------------
-	rootPath := strings.TrimSpace(string(tmpRootPath))
-	err = os.Chdir(rootPath)
-	return err
+	if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return rel
+	}
+	return path
 }
@@ -1,14 +1,22 @@
 package lint_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/lagarciag/codenanny/config"
 	"github.com/lagarciag/codenanny/installer"
 	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/linters"
 	"github.com/lagarciag/codenanny/parser"
 )
 
@@ -23,10 +31,27 @@ func TestMain(t *testing.M) {
 
 }
 
-func TestLintBasic(t *testing.T) {
-	if err := installer.CheckExternalDependencies(); err != nil {
+//newRunner returns a runner for the repo with the missing tools disabled
+func newRunner(t *testing.T) (runner *lint.Runner) {
+	rootPath, err := config.GitRoot(".")
+	if err != nil {
+		t.Fatal("Not in a git repo:", err)
+	}
+	conf, err := config.Load(rootPath)
+	if err != nil {
+		t.Fatal("Error loading config:", err)
+	}
+	if runner, err = lint.NewRunner(rootPath, conf); err != nil {
+		t.Fatal(err)
+	}
+	if runner.Disabled, err = installer.CheckExternalDependencies(runner.Registry, runner.IsEnabled); err != nil {
 		t.Error(err)
 	}
+	return runner
+}
+
+func TestLintBasic(t *testing.T) {
+	runner := newRunner(t)
 
 	var1 := "packagewitherrors/packagewitherrors.go"
 	var2 := "parser/parser_test.go"
@@ -34,11 +59,7 @@ func TestLintBasic(t *testing.T) {
 	var4 := "lint/lint.go"
 	argsVars := []string{var1, var2, var3, var4}
 
-	if err := lint.ChgDirToGitRootPath(); err != nil {
-		t.Error("Error changing to root dir")
-	}
-
-	dirList, pkag, err := parser.Parse(argsVars)
+	dirList, pkag, err := parser.ParseDir(runner.Root, argsVars)
 
 	if err != nil {
 		t.Error("Error:", err)
@@ -57,22 +78,23 @@ func TestLintBasic(t *testing.T) {
 	CreateUnCheckedError()
 
 	errCount := 0
+	var issues []lint.Issue
 
-	err = lint.CheckSinglePackages(pkag)
+	issues, err = runner.CheckSinglePackages(context.Background(), pkag)
 
-	if err != nil {
+	if err != nil || len(issues) > 0 {
 		errCount++
 	}
 
-	err = lint.CheckMultiPackages(pkag)
+	issues, err = runner.CheckMultiPackages(context.Background(), pkag)
 
-	if err != nil {
+	if err != nil || len(issues) > 0 {
 		errCount++
 	}
 
-	err = lint.CheckMultiDirs(dirList)
+	issues, err = runner.CheckMultiDirs(context.Background(), dirList)
 
-	if err != nil {
+	if err != nil || len(issues) > 0 {
 		errCount++
 	}
 
@@ -83,16 +105,11 @@ func TestLintBasic(t *testing.T) {
 }
 
 func TestVet(t *testing.T) {
-	if err := installer.CheckExternalDependencies(); err != nil {
-		t.Error(err)
-	}
-	if err := lint.ChgDirToGitRootPath(); err != nil {
-		t.Error("Error changing to root dir:", err)
-	}
+	runner := newRunner(t)
 
 	var4 := "lint/lint.go"
 	argsVars := []string{var4}
-	dirList, pkag, err := parser.Parse(argsVars)
+	dirList, pkag, err := parser.ParseDir(runner.Root, argsVars)
 
 	if err != nil {
 		t.Error("Error:", err)
@@ -109,34 +126,35 @@ func TestVet(t *testing.T) {
 	t.Log("DIRs:", dirList)
 
 	errCount := 0
+	var issues []lint.Issue
 
-	err = lint.CheckSinglePackages(pkag)
+	issues, err = runner.CheckSinglePackages(context.Background(), pkag)
 
-	if err != nil {
+	if err != nil || len(issues) > 0 {
 		errCount++
 	}
 
-	err = lint.CheckMultiPackages(pkag)
+	issues, err = runner.CheckMultiPackages(context.Background(), pkag)
 
-	if err != nil {
+	if err != nil || len(issues) > 0 {
 		errCount++
 	}
 
-	err = lint.CheckRecursiveDirs(dirList)
+	issues, err = runner.CheckRecursiveDirs(context.Background(), dirList)
 
-	if err != nil {
+	if err != nil || len(issues) > 0 {
 		errCount++
 	}
 
-	err = lint.CheckMultiPackages(dirList)
+	issues, err = runner.CheckMultiPackages(context.Background(), dirList)
 
-	if err != nil {
+	if err != nil || len(issues) > 0 {
 		errCount++
 	}
 
-	err = lint.CheckMultiDirs(dirList)
+	issues, err = runner.CheckMultiDirs(context.Background(), dirList)
 
-	if err != nil {
+	if err != nil || len(issues) > 0 {
 		errCount++
 	}
 
@@ -146,6 +164,293 @@ func TestVet(t *testing.T) {
 
 }
 
+func TestParseOutput(t *testing.T) {
+	vet, _ := linters.Get("vet")
+	out := []byte("# github.com/lagarciag/codenanny/lint\n" +
+		"./lint/lint_test.go:12:2: unreachable code\n" +
+		"lint/lint.go:7: result of fmt.Sprintf call not used\n")
+
+	issues, err := lint.ParseOutput(vet, out, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 {
+		t.Fatal("Expected 2 issues, got", issues)
+	}
+	expected := lint.Issue{Linter: "vet", Path: "lint/lint_test.go", Line: 12, Col: 2,
+		Message: "unreachable code", Severity: lint.SeverityError}
+	if !reflect.DeepEqual(issues[0], expected) {
+		t.Error("Unexpected issue:", issues[0])
+	}
+	if issues[1].String() != "lint/lint.go:7: result of fmt.Sprintf call not used" {
+		t.Error("Unexpected issue string:", issues[1].String())
+	}
+
+	issues, err = lint.ParseOutput(vet, []byte("can't load package\n"), true)
+	var toolErr *lint.ToolError
+	if len(issues) != 0 || !errors.As(err, &toolErr) || toolErr.Output != "can't load package\n" {
+		t.Error("Unmatched output of a failing linter must be a tool error:", issues, err)
+	}
+	issues, _ = lint.ParseOutput(vet, []byte("all good\n"), false)
+	if len(issues) != 0 {
+		t.Error("Unmatched output of a passing linter is not an issue:", issues)
+	}
+}
+
+func TestParseOutputShorthand(t *testing.T) {
+	lll, _ := linters.Get("lll")
+	issues, err := lint.ParseOutput(lll, []byte("cmd/root.go:40: line is 130 characters\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Line != 40 || issues[0].Message != "line is 130 characters" {
+		t.Error("Unexpected issues:", issues)
+	}
+}
+
+func TestParseOutputSeverity(t *testing.T) {
+	staticcheck, _ := linters.Get("staticcheck")
+	out := []byte("lint/lint.go:12:2: \"io/ioutil\" has been deprecated since Go 1.19 (SA1019)\n" +
+		"lint/lint.go:40:3: this value of err is never used (SA4006)\n" +
+		"lint/issue.go:7:1: should merge variable declaration with assignment on next line (S1021)\n")
+	issues, err := lint.ParseOutput(staticcheck, out, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 3 || issues[0].Category != "SA1019" || issues[1].Category != "SA4006" {
+		t.Fatal("The check codes must be the categories:", issues)
+	}
+
+	golint, _ := linters.Get("golint")
+	issues, err = lint.ParseOutput(golint, []byte("a.go:3:1: dates must be in ISO8601\na.go:4:1: wrong code SA4006\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 || issues[0].Category != "" || issues[1].Category != "" {
+		t.Error("Only the check codes in parentheses must be categories:", issues)
+	}
+	issues, _ = lint.ParseOutput(staticcheck, out, true)
+
+	runner, err := lint.NewRunner(t.TempDir(), config.CodeNannyConfig{
+		Severity: map[string]config.LinterSeverity{
+			"staticcheck": {Level: "warning", Rules: map[string]string{"SA1019": "info"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner.ApplySeverity(issues)
+	for id, expected := range []lint.Severity{lint.SeverityInfo, lint.SeverityWarning, lint.SeverityWarning} {
+		if issues[id].Severity != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, issues[id].Category, issues[id].Severity)
+		}
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	issues := []lint.Issue{
+		{Linter: "vet", Path: "a.go", Line: 3, Col: 2, Message: "unreachable code"},
+		{Linter: "staticcheck", Path: "a.go", Line: 3, Col: 1, Message: "Unreachable code. (SA4017)"},
+		{Linter: "gosimple", Path: "a.go", Line: 3, Message: "unreachable code",
+			Fixes: []lint.Fix{{Message: "remove it"}}},
+		{Linter: "golint", Path: "a.go", Line: 3, Message: "exported func A should have comment"},
+		{Linter: "errcheck", Path: "a.go", Line: 7, Message: "f.Close()", Category: "unchecked-error"},
+		{Linter: "staticcheck", Path: "a.go", Line: 7, Message: "error is not checked", Category: "unchecked-error"},
+		{Linter: "vet", Path: "b.go", Line: 3, Message: "unreachable code"},
+	}
+
+	deduped := lint.Dedupe(issues, []string{"staticcheck", "vet"})
+	if len(deduped) != 4 {
+		t.Fatal("Expected 4 issues, got", deduped)
+	}
+	merged := deduped[0]
+	if merged.Linter != "staticcheck" || merged.Col != 1 || !reflect.DeepEqual(merged.Reporters(), []string{"staticcheck", "vet", "gosimple"}) {
+		t.Error("The linter with precedence must win, the others must be listed:", merged, merged.Reporters())
+	}
+	if len(merged.Fixes) != 1 {
+		t.Error("The fixes of a merged duplicate must be kept:", merged.Fixes)
+	}
+	if deduped[1].Linter != "golint" || len(deduped[1].Linters) != 0 {
+		t.Error("Different issues on the same line must be kept:", deduped[1])
+	}
+	if deduped[2].Linter != "staticcheck" || !reflect.DeepEqual(deduped[2].Reporters(), []string{"staticcheck", "errcheck"}) {
+		t.Error("Issues of the same category must be merged:", deduped[2])
+	}
+	if deduped[3].Path != "b.go" {
+		t.Error("Issues in different files must be kept:", deduped[3])
+	}
+
+	if deduped = lint.Dedupe(issues[:3], nil); deduped[0].Linter != "gosimple" {
+		t.Error("Without precedence the first linter by name must win:", deduped[0])
+	}
+
+	same := []lint.Issue{
+		{Linter: "staticcheck", Path: "a.go", Line: 3, Col: 2, Message: "this value of x is never used", Category: "SA4006"},
+		{Linter: "staticcheck", Path: "a.go", Line: 3, Col: 9, Message: "this value of y is never used", Category: "SA4006"},
+		{Linter: "vet", Path: "a.go", Line: 3, Col: 9, Message: "this value of y is never used"},
+	}
+	if deduped = lint.Dedupe(same, nil); len(deduped) != 2 {
+		t.Error("The findings of a linter must never be merged together:", deduped)
+	}
+}
+
+func TestCheckFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.go"), []byte("package bad\nfunc  Bad( ) {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner, err := lint.NewRunner(dir, config.CodeNannyConfig{
+		Disabled: map[string]bool{"dupl": true, "goimports": true, "lll": true, "misspell": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issues, err := runner.CheckFiles(context.Background(), []string{"bad.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Linter != "gofmt" || issues[0].Path != "bad.go" {
+		t.Error("gofmt should report the unformatted file:", issues)
+	}
+
+	runner.Disabled = map[string]bool{"gofmt": true}
+	if issues, _ = runner.CheckFiles(context.Background(), []string{"bad.go"}); len(issues) != 0 {
+		t.Error("Disabled linters must not run:", issues)
+	}
+}
+
+func TestConcurrentRunners(t *testing.T) {
+	sources := map[string]string{
+		"clean": "package clean\n\nfunc Clean() {}\n",
+		"bad":   "package bad\nfunc  Bad( ) {}\n",
+	}
+	conf := config.CodeNannyConfig{
+		Disabled: map[string]bool{"dupl": true, "goimports": true, "lll": true, "misspell": true},
+	}
+	runners := make(map[string]*lint.Runner)
+	for name, source := range sources {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runner, err := lint.NewRunner(dir, conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runners[name] = runner
+	}
+
+	found := make(map[string][]lint.Issue)
+	errs := make(chan error, len(runners))
+	mutex := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	for name, runner := range runners {
+		wg.Add(1)
+		go func(name string, runner *lint.Runner) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				issues, err := runner.CheckFiles(context.Background(), []string{"main.go"})
+				if err != nil {
+					errs <- err
+					return
+				}
+				mutex.Lock()
+				found[name] = issues
+				mutex.Unlock()
+			}
+		}(name, runner)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if len(found["clean"]) != 0 {
+		t.Error("Runners must lint their own root, clean got:", found["clean"])
+	}
+	if len(found["bad"]) != 1 {
+		t.Error("Runners must lint their own root, bad got:", found["bad"])
+	}
+}
+
+func TestExecuteOrder(t *testing.T) {
+	sleeper := linters.Linter{
+		Name:    "sleeper",
+		Command: "sh",
+		Args:    []string{"-c", "sleep $0; echo \"x.go:1:1: slept $0\""},
+		Scope:   linters.ScopeDir,
+	}
+	delays := []string{"0.3", "0", "0.2", "0.1"}
+	var tasks []lint.Task
+	for _, delay := range delays {
+		tasks = append(tasks, lint.Task{Linter: sleeper, Targets: []string{delay}})
+	}
+
+	results := (&lint.Runner{Jobs: len(delays)}).Execute(context.Background(), tasks)
+	if len(results) != len(delays) {
+		t.Fatal("Expected one result per task, got", len(results))
+	}
+	for id, result := range results {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		if len(result.Issues) != 1 || result.Issues[0].Message != "slept "+delays[id] {
+			t.Error("Result out of order:", id, result.Issues)
+		}
+	}
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	hung := linters.Linter{
+		Name:    "hung",
+		Command: "sh",
+		Args:    []string{"-c", "sleep 30 & wait"},
+		Scope:   linters.ScopeDir,
+		Timeout: 200 * time.Millisecond,
+	}
+	start := time.Now()
+	results := (&lint.Runner{Jobs: 1}).Execute(context.Background(), []lint.Task{{Linter: hung}})
+
+	var timeoutErr *lint.TimeoutError
+	if !errors.As(results[0].Err, &timeoutErr) || timeoutErr.Linter != "hung" {
+		t.Error("Expected a timeout error, got:", results[0].Err)
+	}
+	if len(results[0].Issues) != 0 {
+		t.Error("A timed out linter must not report issues:", results[0].Issues)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Error("The linter process group was not killed, took", elapsed)
+	}
+}
+
+func TestExecuteCancel(t *testing.T) {
+	sleeper := linters.Linter{Name: "sleeper", Command: "sh", Args: []string{"-c", "sleep $0 & wait"}, Scope: linters.ScopeDir}
+	tasks := []lint.Task{
+		{Linter: sleeper, Targets: []string{"0"}},
+		{Linter: sleeper, Targets: []string{"30"}},
+		{Linter: sleeper, Targets: []string{"30"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(500*time.Millisecond, cancel)
+	start := time.Now()
+	results := (&lint.Runner{Jobs: 2}).Execute(ctx, tasks)
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Error("Cancelling must kill the running linters, took", elapsed)
+	}
+	if results[0].Err != nil {
+		t.Error("The finished task must keep its result:", results[0].Err)
+	}
+	for _, result := range results[1:] {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Error("Interrupted tasks must report the cancellation, got:", result.Err)
+		}
+	}
+}
+
 func CreateUnCheckedError() (err error) {
 	return nil
 }
@@ -170,3 +475,74 @@ func vetShadowError() (err error) {
 	}
 	return err
 }
+
+func TestApplyFixes(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.go"), []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	edit := lint.Edit{Path: "a.go", Start: 2, End: 4, NewText: "ab"}
+	issues := []lint.Issue{
+		{Fixes: []lint.Fix{{Edits: []lint.Edit{edit}}}},
+		{Fixes: []lint.Fix{{Edits: []lint.Edit{edit}}}},
+		{Fixes: []lint.Fix{{Edits: []lint.Edit{{Path: "a.go", Start: 3, End: 5, NewText: "overlap"}}}}},
+		{Fixes: []lint.Fix{{Edits: []lint.Edit{{Path: "a.go", Start: 8, End: 8, NewText: "-"}}}}},
+		{Message: "no fix"},
+	}
+	changed, err := lint.ApplyFixes(root, issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changed, []string{"a.go"}) {
+		t.Error("Unexpected changed files:", changed)
+	}
+	content, _ := os.ReadFile(filepath.Join(root, "a.go"))
+	if string(content) != "01ab4567-89" {
+		t.Error("Same fixes must apply once and overlapping ones not at all:", string(content))
+	}
+
+	contents := map[string][]byte{"a.go": []byte("abcdefghij")}
+	if changed, err = lint.FixContents(root, contents, issues); err != nil || len(changed) != 1 {
+		t.Fatal("Unexpected changed contents:", changed, err)
+	}
+	if string(contents["a.go"]) != "ababefgh-ij" {
+		t.Error("Fixes must apply to the contents:", string(contents["a.go"]))
+	}
+	if content, _ = os.ReadFile(filepath.Join(root, "a.go")); string(content) != "01ab4567-89" {
+		t.Error("Fixing contents must not write the files:", string(content))
+	}
+}
+
+func TestExecuteStages(t *testing.T) {
+	runner, err := lint.NewRunner(t.TempDir(), config.CodeNannyConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner.Jobs = 2
+
+	var mutex sync.Mutex
+	running, most, ran := 0, 0, 0
+	stage := lint.Stage{Name: "sleep", Run: func(ctx context.Context) {
+		mutex.Lock()
+		running++
+		ran++
+		if running > most {
+			most = running
+		}
+		mutex.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+	}}
+	crash := lint.Stage{Name: "crash", Run: func(ctx context.Context) { panic("boom") }}
+
+	results := runner.Execute(context.Background(), nil, stage, stage, crash, stage, stage)
+	if ran != 4 || most > 2 {
+		t.Errorf("The stages must run on the --jobs workers: %d ran, %d at once", ran, most)
+	}
+	var toolErr *lint.ToolError
+	if len(results) != 1 || !errors.As(results[0].Err, &toolErr) || toolErr.Linter != "crash" {
+		t.Error("A stage that panics must get a tool error:", results)
+	}
+}
@@ -0,0 +1,249 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package lint
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/lagarciag/codenanny/linters"
+	log "github.com/sirupsen/logrus"
+)
+
+//Task is a single run of a linter on its targets
+type Task struct {
+	Linter  linters.Linter
+	Targets []string
+}
+
+//Result is the outcome of running a Task
+type Result struct {
+	Task     Task
+	Issues   []Issue
+	Err      error
+	Duration time.Duration
+	//Cached results were replayed from the cache instead of running the linter
+	Cached bool
+	//Tests counts the packages and tests run by the test linters
+	Tests TestSummary
+	//Skipped results were not run, their targets do not compile
+	Skipped bool
+}
+
+//TestCounts counts the outcomes of packages or tests
+type TestCounts struct {
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+//TestSummary tells how the packages and the tests run by go test did
+type TestSummary struct {
+	Packages TestCounts
+	Tests    TestCounts
+}
+
+//Add adds the counts of other to s
+func (s *TestSummary) Add(other TestSummary) {
+	s.Packages.Passed += other.Packages.Passed
+	s.Packages.Failed += other.Packages.Failed
+	s.Packages.Skipped += other.Packages.Skipped
+	s.Tests.Passed += other.Tests.Passed
+	s.Tests.Failed += other.Tests.Failed
+	s.Tests.Skipped += other.Tests.Skipped
+}
+
+//FileTasks returns one task per file linter, each one with all the files
+func (r *Runner) FileTasks(listOfFiles []string) (tasks []Task) {
+	if len(listOfFiles) == 0 {
+		return tasks
+	}
+	for _, linter := range r.EnabledLinters(linters.ScopeFile) {
+		tasks = append(tasks, Task{Linter: linter, Targets: listOfFiles})
+	}
+	return tasks
+}
+
+//SinglePackageTasks returns one task per package and package linter
+func (r *Runner) SinglePackageTasks(listOfPackages []string) (tasks []Task) {
+	packageLinters := r.EnabledLinters(linters.ScopePackage)
+	for _, aPackage := range listOfPackages {
+		for _, linter := range packageLinters {
+			tasks = append(tasks, Task{Linter: linter, Targets: []string{aPackage}})
+		}
+	}
+	return tasks
+}
+
+//MultiPackageTasks returns one task per multi package linter, each one with all the packages
+func (r *Runner) MultiPackageTasks(listOfPackages []string) (tasks []Task) {
+	if len(listOfPackages) == 0 {
+		return tasks
+	}
+	for _, linter := range r.EnabledLinters(linters.ScopePackages) {
+		tasks = append(tasks, Task{Linter: linter, Targets: listOfPackages})
+	}
+	return tasks
+}
+
+//DirTasks returns one task per directory and dir linter
+func (r *Runner) DirTasks(listOfDirs []string) (tasks []Task) {
+	dirLinters := r.EnabledLinters(linters.ScopeDir)
+	for _, aDir := range listOfDirs {
+		for _, linter := range dirLinters {
+			tasks = append(tasks, Task{Linter: linter, Targets: []string{dirTarget(aDir)}})
+		}
+	}
+	return tasks
+}
+
+//RecursiveTasks returns one task per recursive linter on the common root of listOfDirs
+func (r *Runner) RecursiveTasks(listOfDirs []string) (tasks []Task) {
+	var theDir string
+	switch len(listOfDirs) {
+	case 0:
+		log.Warn("CheckRecursiveDir List is empty")
+		return tasks
+	case 1:
+		theDir = dirTarget(listOfDirs[0])
+	default:
+		theDir = "./"
+	}
+	for _, linter := range r.EnabledLinters(linters.ScopeRecursive) {
+		tasks = append(tasks, Task{Linter: linter, Targets: []string{theDir}})
+	}
+	return tasks
+}
+
+//AllTasks returns the tasks of every scope for the passed files, directories and packages
+func (r *Runner) AllTasks(listOfFiles, listOfDirs, listOfPackages []string) (tasks []Task) {
+	tasks = append(tasks, r.FileTasks(listOfFiles)...)
+	tasks = append(tasks, r.SinglePackageTasks(listOfPackages)...)
+	tasks = append(tasks, r.MultiPackageTasks(listOfPackages)...)
+	tasks = append(tasks, r.DirTasks(listOfDirs)...)
+	tasks = append(tasks, r.RecursiveTasks(listOfDirs)...)
+	return tasks
+}
+
+//Stage is work run in process on a slot of the worker pool, like the analyzers or the tests.
+//Run keeps its own results.
+type Stage struct {
+	Name string
+	Run  func(ctx context.Context)
+}
+
+//Execute runs the tasks and the stages on a pool of r.Jobs workers, the stages are started first.
+//Results are returned in the order of tasks, whatever the order they complete in, followed by
+//the *ToolError of the stages that panicked.
+//Once ctx is done the running linters are killed and the pending tasks get ctx error.
+func (r *Runner) Execute(ctx context.Context, tasks []Task, stages ...Stage) (results []Result) {
+	jobs := r.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	results = make([]Result, len(tasks))
+	stageResults := make([]*Result, len(stages))
+	queue := make(chan int)
+	wg := &sync.WaitGroup{}
+
+	worker := func() {
+		defer wg.Done()
+		for id := range queue {
+			if id < len(stages) {
+				stageResults[id] = runStage(ctx, stages[id])
+				continue
+			}
+			id -= len(stages)
+			if ctx.Err() != nil {
+				results[id] = Result{Task: tasks[id], Err: ctx.Err()}
+				continue
+			}
+			results[id] = r.runTask(ctx, tasks[id])
+		}
+	}
+
+	wg.Add(jobs)
+	for w := 0; w < jobs; w++ {
+		go worker()
+	}
+	for id := 0; id < len(stages)+len(tasks); id++ {
+		queue <- id
+	}
+	close(queue)
+	wg.Wait()
+	for _, result := range stageResults {
+		if result != nil {
+			results = append(results, *result)
+		}
+	}
+	return results
+}
+
+//runStage runs stage, a stage that panics gets a result with a *ToolError
+func runStage(ctx context.Context, stage Stage) (result *Result) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result = &Result{Task: Task{Linter: linters.Linter{Name: stage.Name}}, Err: panicError(stage.Name, recovered)}
+		}
+	}()
+	log.Debugf("Running the %s stage", stage.Name)
+	stage.Run(ctx)
+	return nil
+}
+
+//panicError returns the *ToolError of the linter or stage name that panicked with recovered
+func panicError(name string, recovered interface{}) *ToolError {
+	return &ToolError{Linter: name, Reason: "panicked", Output: fmt.Sprintf("%v\n%s", recovered, debug.Stack())}
+}
+
+//runTask runs a single linter invocation and parses its output.
+//A panic is recovered and returned as a *ToolError, it must not take down the other workers.
+func (r *Runner) runTask(ctx context.Context, task Task) (result Result) {
+	result.Task = task
+	start := time.Now()
+	defer func() { result.Duration = time.Since(start) }()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result.Issues = nil
+			result.Err = panicError(task.Linter.Name, recovered)
+		}
+	}()
+
+	log.Debugf("Running %s checker on %v", task.Linter.Name, task.Targets)
+	out, failed, err := r.RunLinter(ctx, task.Linter, task.Targets)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Issues, result.Err = r.readIssuesFromChecker(out, task.Linter, failed)
+	return result
+}
+
+//collect merges the issues of results, returning the first error found
+func collect(results []Result) (issues []Issue, err error) {
+	for _, result := range results {
+		if result.Err != nil && err == nil {
+			err = result.Err
+		}
+		issues = append(issues, result.Issues...)
+	}
+	return issues, err
+}
@@ -0,0 +1,222 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package linters
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+//builtin holds the linters shipped with codenanny. aligncheck, deadcode, gotype, interfacer, structcheck
+//and varcheck are not maintained anymore, gosimple is part of staticcheck: they are shipped disabled
+var builtin = []Linter{
+	{
+		Name:        "aligncheck",
+		Command:     "aligncheck",
+		Scope:       ScopeDir,
+		Pattern:     `^(?:[^:]+: )?(?P<path>[^:]+):(?P<line>\d+):(?P<col>\d+):\s*(?P<message>.+)$`,
+		InstallPath: "github.com/opennota/check/cmd/aligncheck",
+		NeedsTypes:  true,
+	},
+	//coverage is the coverage gate, it does nothing until thresholds are set in the coverage section of .codenanny
+	{
+		Name:    "coverage",
+		Command: "go",
+		Args:    []string{"test", "-covermode=set", "-coverprofile"},
+		Scope:   ScopeCoverage,
+		Enabled: true,
+	},
+	{
+		Name:        "deadcode",
+		Command:     "deadcode",
+		Scope:       ScopeDir,
+		Pattern:     `^(?:[^:]+: )?(?P<path>[^\s:]+\.go):(?P<line>\d+):(?P<col>\d+):\s*(?P<message>.*)$`,
+		InstallPath: "github.com/tsenart/deadcode",
+	},
+	{
+		Name:        "dupl",
+		Command:     "dupl",
+		Args:        []string{"-plumbing", "-threshold", "{duplthreshold}"},
+		Scope:       ScopeFile,
+		Pattern:     `^(?P<path>[^\s][^:]+?\.go):(?P<line>\d+)-\d+:\s*(?P<message>.*)$`,
+		InstallPath: "github.com/mibk/dupl",
+		Enabled:     true,
+		Defaults:    map[string]string{"duplthreshold": "50"},
+	},
+	{
+		Name:        "goconst",
+		Command:     "goconst",
+		Scope:       ScopeDir,
+		InstallPath: "github.com/jgautheron/goconst/cmd/goconst",
+		Enabled:     true,
+	},
+	{
+		Name:        "gocyclo",
+		Command:     "gocyclo",
+		Args:        []string{"-over", "{mincyclo}"},
+		Scope:       ScopeDir,
+		Pattern:     `^(?P<cyclo>\d+)\s+\S+\s(?P<function>\S+)\s+(?P<path>[^:]+):(?P<line>\d+):(\d+)$`,
+		InstallPath: "github.com/alecthomas/gocyclo",
+		Enabled:     true,
+		Defaults:    map[string]string{"mincyclo": "10"},
+	},
+	{
+		Name:        "gofmt",
+		Command:     "gofmt",
+		Args:        []string{"-l", "-s"},
+		FixArgs:     []string{"-s", "-w"},
+		PreviewArgs: []string{"-s"},
+		Scope:       ScopeFile,
+		Pattern:     `^(?P<path>[^\s:]+\.go)$`,
+		Enabled:     true,
+	},
+	{
+		Name:        "goimports",
+		Command:     "goimports",
+		Args:        []string{"-l"},
+		FixArgs:     []string{"-w"},
+		PreviewArgs: []string{"-srcdir", "{file}"},
+		Scope:       ScopeFile,
+		Pattern:     `^(?P<path>[^\s:]+\.go)$`,
+		InstallPath: "golang.org/x/tools/cmd/goimports",
+		Enabled:     true,
+	},
+	{
+		Name:        "golint",
+		Command:     "golint",
+		Args:        []string{"-set_exit_status"},
+		Scope:       ScopePackage,
+		InstallPath: "github.com/golang/lint/golint",
+		Enabled:     true,
+	},
+	{
+		Name:        "gosimple",
+		Command:     "gosimple",
+		Scope:       ScopePackages,
+		InstallPath: "honnef.co/go/simple/cmd/gosimple",
+		NeedsTypes:  true,
+	},
+	{
+		Name:        "gotype",
+		Command:     "gotype",
+		Args:        []string{"-e", "-a"},
+		Scope:       ScopeDir,
+		InstallPath: "golang.org/x/tools/cmd/gotype",
+		NeedsTypes:  true,
+	},
+	{
+		Name:        "interfacer",
+		Command:     "interfacer",
+		Scope:       ScopePackages,
+		InstallPath: "github.com/mvdan/interfacer/cmd/interfacer",
+		NeedsTypes:  true,
+	},
+	{
+		Name:        "lll",
+		Command:     "lll",
+		Args:        []string{"-g", "-l", "{maxlinelength}"},
+		Scope:       ScopeFile,
+		Pattern:     `PATH:LINE:MESSAGE`,
+		InstallPath: "github.com/walle/lll/cmd/lll",
+		Enabled:     true,
+		Defaults:    map[string]string{"maxlinelength": "120"},
+	},
+	{
+		Name:        "misspell",
+		Command:     "misspell",
+		FixArgs:     []string{"-w"},
+		PreviewArgs: []string{"-w"},
+		Scope:       ScopeFile,
+		Pattern:     `PATH:LINE:COL:MESSAGE`,
+		InstallPath: "github.com/client9/misspell/cmd/misspell",
+		Enabled:     true,
+	},
+	{
+		Name:        "staticcheck",
+		Command:     "staticcheck",
+		Scope:       ScopePackages,
+		InstallPath: "honnef.co/go/staticcheck/cmd/staticcheck",
+		Enabled:     true,
+		NeedsTypes:  true,
+	},
+	{
+		Name:        "structcheck",
+		Command:     "structcheck",
+		Args:        []string{"{tests=-t}"},
+		Scope:       ScopeDir,
+		Pattern:     `^(?:[^:]+: )?(?P<path>[^:]+):(?P<line>\d+):(?P<col>\d+):\s*(?P<message>.+)$`,
+		InstallPath: "github.com/opennota/check/cmd/structcheck",
+		Defaults:    map[string]string{"tests": "false"},
+		NeedsTypes:  true,
+	},
+	//test runs the tests, the failing ones and the testify assertions become issues.
+	//It is slow, so it only runs when enabled in .codenanny or asked with --test.
+	{
+		Name:    "test",
+		Command: "go",
+		Args:    []string{"test", "-json"},
+		Scope:   ScopeTest,
+	},
+	{
+		Name:        "unconvert",
+		Command:     "unconvert",
+		FixArgs:     []string{"-apply"},
+		Scope:       ScopePackages,
+		InstallPath: "github.com/mdempsky/unconvert",
+		Enabled:     true,
+		NeedsTypes:  true,
+	},
+	{
+		Name:        "varcheck",
+		Command:     "varcheck",
+		Scope:       ScopeDir,
+		Pattern:     `^(?:[^:]+: )?(?P<path>[^:]+):(?P<line>\d+):(?P<col>\d+):[\s\t]+(?P<message>.*)$`,
+		InstallPath: "github.com/opennota/check/cmd/varcheck",
+		NeedsTypes:  true,
+	},
+	//vet and vetshadow are replaced by the vet and shadow analyzers, which run in process
+	{
+		Name:       "vet",
+		Command:    "go",
+		Args:       []string{"vet"},
+		Scope:      ScopePackages,
+		NeedsTypes: true,
+	},
+	{
+		Name:       "vetshadow",
+		Command:    "go",
+		Args:       []string{"tool", "vet", "-shadow=true"},
+		Scope:      ScopeRecursive,
+		NeedsTypes: true,
+	},
+}
+
+//Builtin returns a new registry holding the linters shipped with codenanny
+func Builtin() *Registry {
+	r := NewRegistry()
+	for _, l := range builtin {
+		if err := r.Register(l); err != nil {
+			log.Fatal("Invalid builtin linter:", err)
+		}
+	}
+	for _, l := range analyzers() {
+		if err := r.Register(l); err != nil {
+			log.Fatal("Invalid builtin analyzer:", err)
+		}
+	}
+	return r
+}
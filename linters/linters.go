@@ -0,0 +1,238 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+//Package linters declares the linters and code checkers codenanny knows how to run
+package linters
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+//DefaultPattern parses the usual path:line:col: message output, col being optional
+const DefaultPattern = `^(?P<path>[^\s:]+\.go):(?P<line>\d+)(?::(?P<col>\d+))?:\s*(?P<message>.*)$`
+
+//placeholder matches the {name} and {name=text} templates used in Args
+var placeholder = regexp.MustCompile(`\{[A-Za-z0-9_]+(=[^}]*)?\}`)
+
+//shorthands are the gometalinter style patterns accepted in Pattern
+var shorthands = map[string]string{
+	"PATH:LINE:COL:MESSAGE": `^(?P<path>[^\s:]+\.go):(?P<line>\d+):(?P<col>\d+):\s*(?P<message>.*)$`,
+	"PATH:LINE:MESSAGE":     `^(?P<path>[^\s:]+\.go):(?P<line>\d+):\s*(?P<message>.*)$`,
+}
+
+//Scope describes which targets a linter is invoked with
+type Scope string
+
+const (
+	//ScopePackage runs the linter once for every package
+	ScopePackage Scope = "package"
+	//ScopePackages runs the linter once with all the packages as arguments
+	ScopePackages Scope = "packages"
+	//ScopeDir runs the linter once for every directory
+	ScopeDir Scope = "dir"
+	//ScopeRecursive runs the linter once on the common root of all the directories
+	ScopeRecursive Scope = "recursive"
+	//ScopeFile runs the linter once with all the files as arguments
+	ScopeFile Scope = "file"
+	//ScopeAnalysis runs the linter Analyzer in process on the loaded packages
+	ScopeAnalysis Scope = "analysis"
+	//ScopeTest runs the linter once with all the packages as arguments, it prints go test -json events
+	ScopeTest Scope = "test"
+	//ScopeCoverage runs the linter once with the path of the coverage profile to write and the changed packages as arguments
+	ScopeCoverage Scope = "coverage"
+)
+
+//Linter describes an external linter or code checker
+type Linter struct {
+	//Name is the key used in .codenanny to refer to this linter
+	Name string
+	//Command is the binary to execute
+	Command string
+	//Args are passed to Command before the targets
+	Args []string
+	//FixArgs replace Args when the linter is asked to fix the code, empty if it can't
+	FixArgs []string
+	//PreviewArgs make a file fixer print the fixed content of the file it reads on its standard input,
+	//{file} being the path of the file. Fixers without them can't tell what they would change.
+	PreviewArgs []string
+	//Scope decides which targets are appended to Args
+	Scope Scope
+	//Pattern is the regular expression used to parse the linter output
+	Pattern string
+	//InstallPath is the go get path of the linter, empty if it ships with go
+	InstallPath string
+	//Enabled tells if the linter runs when .codenanny does not say otherwise
+	Enabled bool
+	//Defaults holds the values of the {placeholders} in Args not set in .codenanny
+	Defaults map[string]string
+	//Timeout is the time the linter is allowed to run, zero uses the global timeout
+	Timeout time.Duration
+	//Analyzer is run in process instead of Command by ScopeAnalysis linters
+	Analyzer *analysis.Analyzer
+	//NeedsTypes linters type check the code, they are not run on code that does not compile
+	NeedsTypes bool
+}
+
+//CanFix returns true if the linter knows how to fix what it reports
+func (l Linter) CanFix() bool {
+	return len(l.FixArgs) > 0
+}
+
+//CanPreview returns true if the linter can tell what it would fix without writing to the files
+func (l Linter) CanPreview() bool {
+	return l.CanFix() && len(l.PreviewArgs) > 0 && l.Scope == ScopeFile
+}
+
+//Regexp compiles the linter output pattern in multi line mode
+func (l Linter) Regexp() (re *regexp.Regexp, err error) {
+	pattern := l.Pattern
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+	if expanded, found := shorthands[pattern]; found {
+		pattern = expanded
+	}
+	re, err = regexp.Compile("(?m)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("linter %s has an invalid pattern:%s", l.Name, err.Error())
+	}
+	return re, nil
+}
+
+//ExpandArgs replaces the {name} and {name=text} placeholders found in args.
+//{name} is replaced by the value of name, {name=text} by text when name is
+//true and by nothing otherwise. Values in settings override the linter Defaults.
+func (l Linter) ExpandArgs(args []string, settings map[string]string) (expanded []string, err error) {
+	values := make(map[string]string, len(l.Defaults)+len(settings))
+	for key, value := range l.Defaults {
+		values[key] = value
+	}
+	for key, value := range settings {
+		values[key] = value
+	}
+
+	expanded = make([]string, 0, len(args))
+	for _, arg := range args {
+		var missing string
+		result := placeholder.ReplaceAllStringFunc(arg, func(match string) string {
+			name, text, conditional := strings.Cut(match[1:len(match)-1], "=")
+			value, found := values[name]
+			if !found || value == "" {
+				missing = name
+				return ""
+			}
+			if !conditional {
+				return value
+			}
+			if enabled, _ := strconv.ParseBool(value); enabled {
+				return text
+			}
+			return ""
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("linter %s: no value for placeholder {%s}, set it in the settings section of .codenanny", l.Name, missing)
+		}
+		if result == "" && arg != "" {
+			continue
+		}
+		expanded = append(expanded, result)
+	}
+	return expanded, nil
+}
+
+//Registry holds a set of linters indexed by name
+type Registry struct {
+	byName map[string]Linter
+}
+
+//NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]Linter)}
+}
+
+//Register adds a linter to the registry
+func (r *Registry) Register(l Linter) (err error) {
+	if l.Name == "" {
+		return fmt.Errorf("linter has no name")
+	}
+	switch l.Scope {
+	case ScopeAnalysis:
+		if l.Analyzer == nil {
+			return fmt.Errorf("linter %s has no analyzer", l.Name)
+		}
+	case ScopeFile, ScopePackage, ScopePackages, ScopeDir, ScopeRecursive, ScopeTest, ScopeCoverage:
+		if l.Command == "" {
+			return fmt.Errorf("linter %s has no command", l.Name)
+		}
+	default:
+		return fmt.Errorf("linter %s has unknown scope %q", l.Name, l.Scope)
+	}
+	if _, err := l.Regexp(); err != nil {
+		return err
+	}
+	if _, found := r.byName[l.Name]; found {
+		return fmt.Errorf("linter %s is already registered", l.Name)
+	}
+	r.byName[l.Name] = l
+	return nil
+}
+
+//Get returns the linter registered under name
+func (r *Registry) Get(name string) (l Linter, found bool) {
+	l, found = r.byName[name]
+	return l, found
+}
+
+//All returns every registered linter sorted by name
+func (r *Registry) All() (all []Linter) {
+	all = make([]Linter, 0, len(r.byName))
+	for _, l := range r.byName {
+		all = append(all, l)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+//ByScope returns the registered linters with the given scope sorted by name
+func (r *Registry) ByScope(scope Scope) (list []Linter) {
+	for _, l := range r.All() {
+		if l.Scope == scope {
+			list = append(list, l)
+		}
+	}
+	return list
+}
+
+//Default holds the builtin linters, runs add their custom linters to a copy made by Builtin
+var Default = Builtin()
+
+//Get returns the linter registered under name in the Default registry
+func Get(name string) (Linter, bool) {
+	return Default.Get(name)
+}
+
+//All returns every linter in the Default registry
+func All() []Linter {
+	return Default.All()
+}
@@ -0,0 +1,107 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package linters_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lagarciag/codenanny/linters"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestMain(t *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	log.SetFormatter(&log.TextFormatter{})
+	v := t.Run()
+	os.Exit(v)
+
+}
+
+func TestBuiltinRegistry(t *testing.T) {
+	vet, found := linters.Get("vet")
+	if !found {
+		t.Fatal("vet must be registered")
+	}
+	if vet.Command != "go" || vet.Scope != linters.ScopePackages {
+		t.Error("Unexpected vet declaration:", vet)
+	}
+
+	all := linters.All()
+	for i := 1; i < len(all); i++ {
+		if all[i-1].Name >= all[i].Name {
+			t.Error("Linters must be sorted by name:", all[i-1].Name, all[i].Name)
+		}
+	}
+
+	for _, l := range linters.Default.ByScope(linters.ScopePackage) {
+		if l.Scope != linters.ScopePackage {
+			t.Error("ByScope returned linter with wrong scope:", l.Name)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	r := linters.NewRegistry()
+	if err := r.Register(linters.Linter{Name: "foo", Command: "foo", Scope: linters.ScopeDir}); err != nil {
+		t.Error("Register failed:", err)
+	}
+	if err := r.Register(linters.Linter{Name: "foo", Command: "foo", Scope: linters.ScopeDir}); err == nil {
+		t.Error("Registering twice must fail")
+	}
+	if err := r.Register(linters.Linter{Name: "bar", Command: "bar", Scope: "nowhere"}); err == nil {
+		t.Error("Unknown scope must fail")
+	}
+	if err := r.Register(linters.Linter{Name: "baz", Scope: linters.ScopeDir}); err == nil {
+		t.Error("Missing command must fail")
+	}
+	if len(r.All()) != 1 {
+		t.Error("Registry should hold 1 linter, got", len(r.All()))
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	gocyclo, _ := linters.Get("gocyclo")
+	args, err := gocyclo.ExpandArgs(gocyclo.Args, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(args, " ") != "-over 10" {
+		t.Error("Default mincyclo not applied:", args)
+	}
+	args, _ = gocyclo.ExpandArgs(gocyclo.Args, map[string]string{"mincyclo": "25"})
+	if strings.Join(args, " ") != "-over 25" {
+		t.Error("Configured mincyclo not applied:", args)
+	}
+
+	structcheck, _ := linters.Get("structcheck")
+	args, _ = structcheck.ExpandArgs(structcheck.Args, nil)
+	if len(args) != 0 {
+		t.Error("Disabled conditional placeholder must be dropped:", args)
+	}
+	args, _ = structcheck.ExpandArgs(structcheck.Args, map[string]string{"tests": "true"})
+	if strings.Join(args, " ") != "-t" {
+		t.Error("Enabled conditional placeholder must expand:", args)
+	}
+
+	custom := linters.Linter{Name: "custom", Args: []string{"-level={level}"}}
+	if _, err = custom.ExpandArgs(custom.Args, nil); err == nil || !strings.Contains(err.Error(), "{level}") {
+		t.Error("Missing placeholder value must name the placeholder:", err)
+	}
+}
@@ -0,0 +1,150 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+//Package loader loads the packages being linted once per run, so every
+//builtin checker shares their syntax and type information
+package loader
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/tools/go/packages"
+)
+
+//Mode is what the checkers need: syntax and types of the packages and of their
+//dependencies, and the module of each one to tell the versions of the dependencies
+const Mode = packages.LoadAllSyntax | packages.NeedModule
+
+//Program is the set of packages loaded for a run
+type Program struct {
+	//Root is the directory the packages were loaded from
+	Root string
+	//Packages are the loaded packages, test variants included
+	Packages []*packages.Package
+}
+
+//Load loads the packages matching patterns, with their tests, from root
+func Load(ctx context.Context, root string, patterns []string) (program *Program, err error) {
+	return LoadOverlay(ctx, root, patterns, nil)
+}
+
+//LoadOverlay is Load with the content of some files replaced by the one of overlay,
+//by path relative to root, so code that is not written yet can be checked
+func LoadOverlay(ctx context.Context, root string, patterns []string, overlay map[string][]byte) (program *Program, err error) {
+	program = &Program{Root: root}
+	if len(patterns) == 0 {
+		return program, nil
+	}
+	start := time.Now()
+	cfg := &packages.Config{
+		Context: ctx,
+		Mode:    Mode,
+		Dir:     root,
+		Tests:   true,
+	}
+	if len(overlay) > 0 {
+		cfg.Overlay = make(map[string][]byte, len(overlay))
+		for path, content := range overlay {
+			cfg.Overlay[filepath.Join(root, path)] = content
+		}
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("could not load packages:%s", err.Error())
+	}
+	for _, pkg := range pkgs {
+		//Directories without go files match no package
+		if len(pkg.GoFiles) == 0 && len(pkg.CompiledGoFiles) == 0 {
+			log.Debug("No go files in:", pkg.ID)
+			continue
+		}
+		program.Packages = append(program.Packages, pkg)
+	}
+	log.Debugf("Loaded %d packages in %s", len(program.Packages), time.Since(start))
+	return program, nil
+}
+
+//DirPatterns returns the package patterns of dirs, relative to the root
+func DirPatterns(dirs []string) (patterns []string) {
+	for _, dir := range dirs {
+		if filepath.IsAbs(dir) {
+			patterns = append(patterns, dir)
+			continue
+		}
+		dir = filepath.ToSlash(filepath.Clean(dir))
+		if dir != "." {
+			dir = "./" + dir
+		}
+		patterns = append(patterns, dir)
+	}
+	return patterns
+}
+
+//Roots returns the packages to check: a package with tests is replaced by its
+//test variant, which has the same files and the test ones, and test mains are dropped
+func (p *Program) Roots() (roots []*packages.Package) {
+	ids := make(map[string]bool, len(p.Packages))
+	for _, pkg := range p.Packages {
+		ids[pkg.ID] = true
+	}
+	for _, pkg := range p.Packages {
+		if strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+		if ids[fmt.Sprintf("%s [%s.test]", pkg.ID, pkg.ID)] {
+			continue
+		}
+		roots = append(roots, pkg)
+	}
+	return roots
+}
+
+//PkgPaths returns the sorted import paths of the loaded packages, without test variants nor test mains
+func (p *Program) PkgPaths() (paths []string) {
+	unique := make(map[string]bool)
+	for _, pkg := range p.Packages {
+		if pkg.ID != pkg.PkgPath || strings.HasSuffix(pkg.PkgPath, "_test") || strings.HasSuffix(pkg.PkgPath, ".test") {
+			continue
+		}
+		unique[pkg.PkgPath] = true
+	}
+	for path := range unique {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+//Dirs returns the directory of each loaded package by import path, relative to the program root
+func (p *Program) Dirs() (dirs map[string]string) {
+	dirs = make(map[string]string)
+	for _, pkg := range p.Packages {
+		if len(pkg.GoFiles) == 0 {
+			continue
+		}
+		if dir, err := filepath.Rel(p.Root, filepath.Dir(pkg.GoFiles[0])); err == nil {
+			dirs[pkg.PkgPath] = dir
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,72 @@
+package loader_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/lagarciag/codenanny/loader"
+)
+
+func TestMain(t *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	formatter := &log.TextFormatter{}
+	formatter.ForceColors = true
+	formatter.DisableTimestamp = true
+	log.SetFormatter(formatter)
+	v := t.Run()
+	os.Exit(v)
+
+}
+
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":              "module example.com/nanny\n\ngo 1.21\n",
+		"nanny.go":            "package nanny\n\nimport \"example.com/nanny/util\"\n\nvar Two = util.One + 1\n",
+		"util/util.go":        "package util\n\nconst One = 1\n",
+		"util/util_test.go":   "package util\n\nimport \"testing\"\n\nfunc TestOne(t *testing.T) {}\n",
+		"util/ext_test.go":    "package util_test\n\nimport \"testing\"\n\nfunc TestExt(t *testing.T) {}\n",
+		"testdata/README.txt": "no go files here\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	patterns := loader.DirPatterns([]string{".", "util", "testdata"})
+	if !reflect.DeepEqual(patterns, []string{".", "./util", "./testdata"}) {
+		t.Error("Unexpected patterns:", patterns)
+	}
+	program, err := loader.Load(context.Background(), root, patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if paths := program.PkgPaths(); !reflect.DeepEqual(paths, []string{"example.com/nanny", "example.com/nanny/util"}) {
+		t.Error("Unexpected package paths:", paths)
+	}
+	var ids []string
+	for _, pkg := range program.Roots() {
+		ids = append(ids, pkg.ID)
+		if pkg.Types == nil || len(pkg.Syntax) == 0 {
+			t.Error("Packages must be loaded with syntax and types:", pkg.ID)
+		}
+	}
+	sort.Strings(ids)
+	expected := []string{"example.com/nanny", "example.com/nanny/util [example.com/nanny/util.test]",
+		"example.com/nanny/util_test [example.com/nanny/util.test]"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Error("Unexpected root packages:", ids)
+	}
+}
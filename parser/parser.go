@@ -28,42 +28,42 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 )
 
-//Parse parses the provided list of modified files
+//Parse parses the provided list of modified files, relative to the working dir
 func Parse(stringList []string) (dir []string, pkag []string, err error) {
+	return ParseDir(".", stringList)
+}
 
-	/*
-		//Find out what the Root Path is
-		cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-		tmpRootPath, err := cmd.Output()
-		if err != nil {
-			return dir, pkag, err
-		}
-
-		//Trim return character
-		rootPath := strings.TrimSpace(string(tmpRootPath))
-		err = os.Chdir(rootPath)
-		if err != nil {
-			return dir, pkag, err
-		}
-	*/
+//ParseDir parses the provided list of modified files, relative to rootPath
+func ParseDir(rootPath string, stringList []string) (dir []string, pkag []string, err error) {
 	//log.Debug("Parser:",stringList)
 	dir, err = getUniqueDirs(stringList)
 
 	log.Info("DIR to parse", dir)
 
-	pkag, err = getUniquePkgs(dir)
+	pkag, err = getUniquePkgs(rootPath, dir)
 
 	return dir, pkag, err
 }
 
-func getUniquePkgs(dirList []string) (pkgList []string, err error) {
+//Dirs returns the sorted list of unique directories of the provided list of modified files
+func Dirs(stringList []string) (dir []string) {
+	dir, _ = getUniqueDirs(stringList)
+	sort.Strings(dir)
+	return dir
+}
+
+func getUniquePkgs(rootPath string, dirList []string) (pkgList []string, err error) {
 	pkgHash := make(map[string]bool)
-	rawPkagList, err := readListOfPackages()
+	rawPkagList, err := readListOfPackages(rootPath)
+	if err != nil {
+		return pkgList, err
+	}
 	fuulRootPackage := rawPkagList[0]
 	r, err := regexp.Compile(`\w+$`)
 	rootPackage := r.FindString(fuulRootPackage)
@@ -111,12 +111,13 @@ func getUniqueDirs(stringList []string) (dir []string, err error) {
 
 }
 
-func readListOfPackages() (pkag []string, err error) {
+func readListOfPackages(rootPath string) (pkag []string, err error) {
 
 	//-------------------------------------------
 	//          Read list of packages
 	//--------------------------------------------
 	golistCmd := exec.Command("go", "list", "./...")
+	golistCmd.Dir = rootPath
 	tmpGoList, err := golistCmd.Output()
 	if err != nil {
 		log.Error("Parser failed in go list")
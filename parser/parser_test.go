@@ -5,10 +5,8 @@ import (
 	"testing"
 
 	log "github.com/sirupsen/logrus"
-	"github.com/lagarciag/codenanny/installer"
+	"github.com/lagarciag/codenanny/config"
 	"github.com/lagarciag/codenanny/parser"
-
-	"github.com/lagarciag/codenanny/lint"
 )
 
 func TestMain(t *testing.M) {
@@ -24,12 +22,9 @@ func TestMain(t *testing.M) {
 
 func TestParserBasic(t *testing.T) {
 	//gopath := os.Getenv("GOPATH")
-	if err := installer.CheckExternalDependencies(); err != nil {
-		t.Error("Error found checking dependencies")
-	}
-
-	if err := lint.ChgDirToGitRootPath(); err != nil {
-		t.Error(err)
+	rootPath, err := config.GitRoot(".")
+	if err != nil {
+		t.Fatal(err)
 	}
 
 	var1 := "parser/parser.go"
@@ -37,7 +32,7 @@ func TestParserBasic(t *testing.T) {
 	var3 := "cmd/root.go"
 	argsSlice := []string{var1, var2, var3}
 	log.Info("string to parse:", argsSlice)
-	dirList, pkag, err := parser.Parse(argsSlice)
+	dirList, pkag, err := parser.ParseDir(rootPath, argsSlice)
 
 	if err != nil {
 		t.Error("Error:", err)
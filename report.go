@@ -0,0 +1,274 @@
+// Copyright © 2016 Luis Garcia <luis.a.garcia@hpe.com>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package codenanny
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lagarciag/codenanny/build"
+	"github.com/lagarciag/codenanny/lint"
+)
+
+//Verdict is the overall outcome of a run
+type Verdict string
+
+const (
+	//VerdictPass means every linter ran and found nothing serious enough to fail the run
+	VerdictPass Verdict = "pass"
+	//VerdictIssues means every linter ran and some found issues serious enough to fail the run
+	VerdictIssues Verdict = "issues"
+	//VerdictError means some linter had a tool error, failed or timed out, or some code does not compile
+	VerdictError Verdict = "error"
+	//VerdictInterrupted means the run was stopped before every linter finished
+	VerdictInterrupted Verdict = "interrupted"
+)
+
+//Status is the outcome of a linter in a run
+type Status string
+
+const (
+	//StatusPassed linters found nothing
+	StatusPassed Status = "passed"
+	//StatusSkipped linters need type information, they were not run on some code because it does not compile
+	StatusSkipped Status = "skipped"
+	//StatusIssues linters found issues
+	StatusIssues Status = "issues"
+	//StatusToolError linters are not installed, could not be run, crashed or printed something that is not issues
+	StatusToolError Status = "tool error"
+	//StatusFailed linters could not run because of their configuration
+	StatusFailed Status = "failed"
+	//StatusTimeout linters were killed when their timeout expired
+	StatusTimeout Status = "timeout"
+	//StatusInterrupted linters were stopped before finishing
+	StatusInterrupted Status = "interrupted"
+)
+
+//statusRank orders the statuses of the tasks of a linter, the highest one is the linter status
+var statusRank = map[Status]int{
+	StatusPassed:      0,
+	StatusSkipped:     1,
+	StatusIssues:      2,
+	StatusToolError:   3,
+	StatusFailed:      4,
+	StatusTimeout:     5,
+	StatusInterrupted: 6,
+}
+
+//LinterReport tells how a linter did in a run
+type LinterReport struct {
+	Name   string
+	Status Status
+	//Tasks is the number of times the linter was invoked
+	Tasks int
+	//Cached is the number of tasks replayed from the cache
+	Cached int
+	//Issues is the number of issues the linter found
+	Issues int
+	//Duration adds up the time of every invocation
+	Duration time.Duration
+	//Errors holds the reasons of the failed invocations
+	Errors []error
+}
+
+//Report is the result of a run
+type Report struct {
+	//Root is the directory the linters ran in
+	Root string
+	//Issues are the findings of every linter sorted by location, the ones reported by several linters merged
+	Issues []lint.Issue
+	//Counts is the number of issues of each severity
+	Counts map[lint.Severity]int
+	//Tests counts the packages and tests run by the test linters
+	Tests lint.TestSummary
+	//Baselined is the number of issues left out because the baseline lists them
+	Baselined int
+	//Suppressed is the number of issues silenced by the //nanny:ignore directives of the code
+	Suppressed int
+	//Fixed are the files changed by the fixers when Options.Fix is set, relative to Root and sorted
+	Fixed []string
+	//Diff is the unified diff of what the fixers would change when Options.Diff is set
+	Diff string
+	//Linters reports every enabled linter sorted by name
+	Linters []LinterReport
+	//Duration is the wall time of the run
+	Duration time.Duration
+	Verdict  Verdict
+}
+
+//newReport builds the report of the results of a run of runner, interrupted tells if the run was stopped.
+//Only the issues at least as serious as failOn fail the run.
+func newReport(runner *lint.Runner, results []lint.Result, interrupted bool, failOn lint.Severity, duration time.Duration) (report *Report) {
+	report = &Report{Root: runner.Root, Duration: duration}
+
+	byName := make(map[string]*LinterReport)
+	for _, result := range results {
+		linterReport, found := byName[result.Task.Linter.Name]
+		if !found {
+			linterReport = &LinterReport{Name: result.Task.Linter.Name, Status: StatusPassed}
+			byName[result.Task.Linter.Name] = linterReport
+		}
+		status := taskStatus(result)
+		if statusRank[status] > statusRank[linterReport.Status] {
+			linterReport.Status = status
+		}
+		if result.Err != nil {
+			linterReport.Errors = append(linterReport.Errors, result.Err)
+		}
+		linterReport.Tasks++
+		if result.Cached {
+			linterReport.Cached++
+		}
+		linterReport.Issues += len(result.Issues)
+		linterReport.Duration += result.Duration
+		report.Issues = append(report.Issues, result.Issues...)
+		report.Tests.Add(result.Tests)
+	}
+	runner.ApplySeverity(report.Issues)
+	report.Issues = lint.Dedupe(report.Issues, runner.Config.Precedence)
+	lint.SortIssues(report.Issues)
+
+	for _, linter := range runner.Registry.All() {
+		if !runner.IsEnabled(linter) {
+			continue
+		}
+		linterReport, found := byName[linter.Name]
+		switch {
+		case runner.Disabled[linter.Name]:
+			linterReport = &LinterReport{Name: linter.Name, Status: StatusToolError,
+				Errors: []error{&lint.ToolError{Linter: linter.Name, Reason: "is not installed"}}}
+		case !found:
+			continue
+		}
+		report.Linters = append(report.Linters, *linterReport)
+		delete(byName, linter.Name)
+	}
+	//What is left was not run by a registered linter, like the compile errors of the build stage
+	for _, linterReport := range byName {
+		report.Linters = append(report.Linters, *linterReport)
+	}
+	sort.Slice(report.Linters, func(i, j int) bool { return report.Linters[i].Name < report.Linters[j].Name })
+	report.judge(interrupted, failOn)
+	return report
+}
+
+//judge counts the issues of the report by severity and sets its verdict
+func (r *Report) judge(interrupted bool, failOn lint.Severity) {
+	r.Counts = make(map[lint.Severity]int)
+	failing := 0
+	for _, issue := range r.Issues {
+		r.Counts[issue.Severity]++
+		if issue.Severity.AtLeast(failOn) {
+			failing++
+		}
+	}
+
+	//Code that does not compile never passes, even when its build issues are suppressed or baselined
+	failed, broken := false, false
+	for _, linterReport := range r.Linters {
+		switch linterReport.Status {
+		case StatusToolError, StatusFailed, StatusTimeout:
+			failed = true
+		case StatusSkipped:
+			broken = true
+		}
+		if linterReport.Name == build.Name && linterReport.Issues > 0 {
+			broken = true
+		}
+	}
+
+	switch {
+	case interrupted:
+		r.Verdict = VerdictInterrupted
+	case failed:
+		r.Verdict = VerdictError
+	case failing > 0:
+		r.Verdict = VerdictIssues
+	case broken:
+		r.Verdict = VerdictError
+	default:
+		r.Verdict = VerdictPass
+	}
+}
+
+//taskStatus returns the status of a single linter invocation
+func taskStatus(result lint.Result) Status {
+	var timeoutErr *lint.TimeoutError
+	var toolErr *lint.ToolError
+	switch {
+	case errors.Is(result.Err, context.Canceled), errors.Is(result.Err, context.DeadlineExceeded):
+		return StatusInterrupted
+	case errors.As(result.Err, &timeoutErr):
+		return StatusTimeout
+	case errors.As(result.Err, &toolErr):
+		return StatusToolError
+	case result.Err != nil:
+		return StatusFailed
+	case len(result.Issues) > 0:
+		return StatusIssues
+	case result.Skipped:
+		return StatusSkipped
+	}
+	return StatusPassed
+}
+
+//ToolErrors returns the tool errors of every linter, with the raw output of the linters that ran
+func (r *Report) ToolErrors() (toolErrors []*lint.ToolError) {
+	return toolErrorsOf(r.Linters)
+}
+
+//toolErrorsOf returns the tool errors of linterReports
+func toolErrorsOf(linterReports []LinterReport) (toolErrors []*lint.ToolError) {
+	for _, linterReport := range linterReports {
+		for _, err := range linterReport.Errors {
+			var toolErr *lint.ToolError
+			if errors.As(err, &toolErr) {
+				toolErrors = append(toolErrors, toolErr)
+			}
+		}
+	}
+	return toolErrors
+}
+
+//Passed returns true when every linter ran and found nothing serious enough to fail the run
+func (r *Report) Passed() bool {
+	return r.Verdict == VerdictPass
+}
+
+//WriteText writes the issues to w, one per line followed by the linters that reported it
+//and by the severity when it is not an error
+func (r *Report) WriteText(w io.Writer) (err error) {
+	for _, issue := range r.Issues {
+		line := fmt.Sprintf("%s (%s)", issue, strings.Join(issue.Reporters(), ", "))
+		if issue.Severity != lint.SeverityError {
+			line += fmt.Sprintf(" [%s]", issue.Severity)
+		}
+		if _, err = fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,390 @@
+/**
+ * Copyright (C) 2015 Hewlett Packard Enterprise Development LP
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+//Package suppress applies the //nanny:ignore and //nanny:file-ignore directives found in the code, and the
+//ignore patterns with an expiry date, to the issues of a run
+package suppress
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lagarciag/codenanny/build"
+	"github.com/lagarciag/codenanny/config"
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/linters"
+	log "github.com/sirupsen/logrus"
+)
+
+//Name names the issues about the suppressions themselves, directives and expiring ignore patterns
+const Name = "suppression"
+
+const (
+	//ignoreDirective suppresses the issues of its line, or of the line or declaration that follows
+	ignoreDirective = "nanny:ignore"
+	//fileIgnoreDirective suppresses the issues of the whole file
+	fileIgnoreDirective = "nanny:file-ignore"
+)
+
+//Directive is a //nanny:ignore or //nanny:file-ignore comment
+type Directive struct {
+	//Path is the file holding the directive, relative to the root
+	Path string
+	//Line and Col are where the comment starts
+	Line int
+	Col  int
+	//File directives suppress the issues of every line of Path
+	File bool
+	//Linters are the linters whose issues are suppressed, every linter when empty or all
+	Linters []string
+	//Reason is the text after --
+	Reason string
+	//Until is the last day the directive applies, zero when it never expires
+	Until time.Time
+	//Owner is who is expected to fix the issues suppressed
+	Owner string
+	//Start and End are the lines the directive applies to
+	Start int
+	End   int
+	//Used is the number of issues the directive suppressed
+	Used int
+	//Hidden are the issues the directive suppressed once expired
+	Hidden []lint.Issue
+	//Err tells why the directive is malformed, it suppresses nothing then
+	Err string
+}
+
+//Parse returns the directives of the go file at path, relative to the root, with content src.
+//A directive at the end of a line of code applies to that line. A directive alone on its line applies to the
+//next line, or to the whole declaration that follows when it starts there, like in the doc of a function.
+func Parse(path string, src []byte) (directives []*Directive, err error) {
+	fset := token.NewFileSet()
+	//Files with syntax errors still have the comments found before the error
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if file == nil {
+		return nil, err
+	}
+	lines := strings.Split(string(src), "\n")
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			text := strings.TrimPrefix(comment.Text, "//")
+			if !strings.HasPrefix(text, "nanny:") {
+				continue
+			}
+			position := fset.Position(comment.Slash)
+			directive := &Directive{Path: path, Line: position.Line, Col: position.Column, Start: position.Line, End: position.Line}
+			body, reason, _ := strings.Cut(text, "--")
+			directive.Reason = strings.TrimSpace(reason)
+			fields := strings.Fields(body)
+			switch fields[0] {
+			case ignoreDirective:
+			case fileIgnoreDirective:
+				directive.File = true
+			default:
+				directive.Err = fmt.Sprintf("unknown directive %s, expected %s or %s", fields[0], ignoreDirective, fileIgnoreDirective)
+			}
+			for _, field := range fields[1:] {
+				key, value, _ := strings.Cut(field, "=")
+				switch key {
+				case "until":
+					if directive.Until, err = config.ParseUntil(value); err != nil {
+						directive.Err = err.Error()
+					}
+					continue
+				case "owner":
+					directive.Owner = value
+					continue
+				}
+				for _, name := range strings.Split(field, ",") {
+					if name != "" {
+						directive.Linters = append(directive.Linters, config.LinterName(name))
+					}
+				}
+			}
+
+			if !directive.File && strings.HasPrefix(strings.TrimSpace(lines[position.Line-1]), "//") {
+				next := fset.Position(group.End()).Line + 1
+				directive.End = next
+				for _, decl := range file.Decls {
+					if fset.Position(decl.Pos()).Line == next {
+						directive.End = fset.Position(decl.End()).Line
+					}
+				}
+			}
+			directives = append(directives, directive)
+		}
+	}
+	return directives, nil
+}
+
+//pattern is an ignore pattern with an expiry date
+type pattern struct {
+	config.IgnorePattern
+	linter string
+	re     *regexp.Regexp
+	until  time.Time
+	hidden []lint.Issue
+}
+
+//datedPatterns returns the ignore patterns of conf with an expiry date by linter, the invalid ones are left out
+func datedPatterns(conf config.CodeNannyConfig) (patterns map[string][]*pattern) {
+	patterns = make(map[string][]*pattern)
+	for name, list := range conf.IgnorePattern {
+		for _, ignore := range list {
+			if ignore.Until == "" {
+				continue
+			}
+			until, err := config.ParseUntil(ignore.Until)
+			if err != nil {
+				continue
+			}
+			re, err := regexp.Compile(ignore.Pattern)
+			if err != nil {
+				continue
+			}
+			patterns[name] = append(patterns[name], &pattern{IgnorePattern: ignore, linter: name, re: re, until: until})
+		}
+	}
+	return patterns
+}
+
+//matches tells if the directive suppresses issue
+func (d *Directive) matches(issue lint.Issue) bool {
+	if d.Err != "" || filepath.Clean(issue.Path) != d.Path {
+		return false
+	}
+	if !d.File && (issue.Line < d.Start || issue.Line > d.End) {
+		return false
+	}
+	if len(d.Linters) == 0 {
+		return true
+	}
+	for _, name := range d.Linters {
+		if name == issue.Linter || name == "all" {
+			return true
+		}
+	}
+	return false
+}
+
+//Apply drops the issues of results suppressed by the directives of their files, or by the ignore patterns of conf
+//with an expiry date, and returns the results left with the number of issues suppressed. Expired suppressions
+//still apply, they are reported with the issues they hide in a result of their own, with the directives of the
+//linted files that are malformed or break the policy of .codenanny; unused ones only when the run was not interrupted.
+func Apply(root string, conf config.CodeNannyConfig, files []string, results []lint.Result, interrupted bool) (kept []lint.Result, suppressed int) {
+	kept, suppressed, problems := apply(root, nil, conf, files, results, interrupted)
+	if len(problems) > 0 {
+		kept = append(kept, lint.Result{Task: lint.Task{Linter: linters.Linter{Name: Name}, Targets: files}, Issues: problems})
+	}
+	return kept, suppressed
+}
+
+//Filter drops the issues of results suppressed the way Apply does, without reporting anything about the
+//suppressions. The directives of the files found in sources, by path relative to root, are read from there.
+func Filter(root string, sources map[string][]byte, conf config.CodeNannyConfig, results []lint.Result) (kept []lint.Result) {
+	kept, _, _ = apply(root, sources, conf, nil, results, true)
+	return kept
+}
+
+//apply is Apply returning the issues about the suppressions apart, sources replacing the content of their files
+func apply(root string, sources map[string][]byte, conf config.CodeNannyConfig, files []string, results []lint.Result, interrupted bool) (kept []lint.Result, suppressed int, problems []lint.Issue) {
+	now := time.Now()
+	policy := conf.Directives
+	registry, err := conf.Registry()
+	if err != nil {
+		registry = linters.Builtin()
+	}
+	linted := make(map[string]bool, len(files))
+	for _, file := range files {
+		linted[filepath.Clean(file)] = true
+	}
+	byFile := make(map[string][]*Directive)
+	load := func(path string) []*Directive {
+		path = filepath.Clean(path)
+		if directives, found := byFile[path]; found || filepath.Ext(path) != ".go" || filepath.IsAbs(path) {
+			return directives
+		}
+		src, found := sources[path]
+		var err error
+		if !found {
+			src, err = ioutil.ReadFile(filepath.Join(root, path))
+		}
+		if err == nil {
+			byFile[path], err = Parse(path, src)
+			checkNames(byFile[path], registry)
+		}
+		if err != nil {
+			log.Debugf("Directives of %s not read:%s", path, err.Error())
+		}
+		return byFile[path]
+	}
+	for file := range linted {
+		load(file)
+	}
+	patterns := datedPatterns(conf)
+
+	//Linters that failed or were skipped may have missed the issues their directives suppress
+	ran := make(map[string]bool)
+	for _, result := range results {
+		name := result.Task.Linter.Name
+		if _, found := ran[name]; !found {
+			ran[name] = true
+		}
+		if result.Err != nil || result.Skipped {
+			ran[name] = false
+		}
+
+		var issues []lint.Issue
+		for _, issue := range result.Issues {
+			if directive := suppressor(load(issue.Path), issue, policy); directive != nil {
+				log.Debugf("%s:%s suppressed by the directive at %s:%d", issue.Linter, issue, directive.Path, directive.Line)
+				directive.Used++
+				if config.Expired(directive.Until, now) {
+					directive.Hidden = append(directive.Hidden, issue)
+				}
+			} else if ignore := matching(patterns[issue.Linter], issue); ignore != nil {
+				log.Debugf("%s:%s suppressed by the ignore pattern %q", issue.Linter, issue, ignore.Pattern)
+				if config.Expired(ignore.until, now) {
+					ignore.hidden = append(ignore.hidden, issue)
+				}
+			} else {
+				issues = append(issues, issue)
+				continue
+			}
+			suppressed++
+		}
+		result.Issues = issues
+		kept = append(kept, result)
+	}
+
+	expired := lint.Severity(conf.ExpiredSeverity())
+	for path, directives := range byFile {
+		for _, directive := range directives {
+			message, severity := "", lint.SeverityError
+			switch {
+			case !linted[path] && len(directive.Hidden) == 0:
+				continue
+			case directive.Err != "":
+				message = directive.Err
+			case policy.RequireReason && directive.Reason == "":
+				message = "directive has no reason, add one after --"
+			case config.Expired(directive.Until, now):
+				message, severity = expiredMessage("directive", directive.Until, directive.Owner, directive.Hidden), expired
+			case policy.ReportUnused && !interrupted && directive.Used == 0 && allRan(directive.Linters, ran):
+				message, severity = "directive suppresses no issue, remove it", lint.SeverityWarning
+			default:
+				continue
+			}
+			problems = append(problems, lint.Issue{
+				Linter:   Name,
+				Path:     path,
+				Line:     directive.Line,
+				Col:      directive.Col,
+				Message:  message,
+				Severity: severity,
+			})
+		}
+	}
+	for _, list := range patterns {
+		for _, ignore := range list {
+			if !config.Expired(ignore.until, now) {
+				continue
+			}
+			problems = append(problems, lint.Issue{
+				Linter:   Name,
+				Path:     ".codenanny",
+				Message:  expiredMessage(fmt.Sprintf("ignore pattern %q of %s", ignore.Pattern, ignore.linter), ignore.until, ignore.Owner, ignore.hidden),
+				Severity: expired,
+			})
+		}
+	}
+	return kept, suppressed, problems
+}
+
+//stages are the names directives may use besides the linters: every linter, and the stages reporting issues of their own
+var stages = map[string]bool{"all": true, Name: true, build.Name: true}
+
+//checkNames marks the directives naming a linter registry does not know as malformed
+func checkNames(directives []*Directive, registry *linters.Registry) {
+	for _, directive := range directives {
+		for _, name := range directive.Linters {
+			if _, found := registry.Get(name); !found && !stages[name] && directive.Err == "" {
+				directive.Err = fmt.Sprintf("unknown linter %s, see codenanny linters", name)
+			}
+		}
+	}
+}
+
+//expiredMessage describes an expired suppression and the issues it hides
+func expiredMessage(what string, until time.Time, owner string, hidden []lint.Issue) string {
+	message := fmt.Sprintf("%s expired on %s", what, until.Format(config.UntilLayout))
+	if owner != "" {
+		message += ", owner " + owner
+	}
+	if len(hidden) == 0 {
+		return message + ", it hides no issue, remove it"
+	}
+	lint.SortIssues(hidden)
+	list := make([]string, 0, len(hidden))
+	for _, issue := range hidden {
+		list = append(list, fmt.Sprintf("%s (%s)", issue, issue.Linter))
+	}
+	count := fmt.Sprintf("%d issues", len(hidden))
+	if len(hidden) == 1 {
+		count = "1 issue"
+	}
+	return fmt.Sprintf("%s, it hides %s: %s", message, count, strings.Join(list, "; "))
+}
+
+//matching returns the first of patterns matching issue, nil if none does
+func matching(patterns []*pattern, issue lint.Issue) *pattern {
+	for _, ignore := range patterns {
+		if ignore.re.MatchString(issue.String()) {
+			return ignore
+		}
+	}
+	return nil
+}
+
+//suppressor returns the first directive of directives suppressing issue, nil if none does
+func suppressor(directives []*Directive, issue lint.Issue, policy config.Directives) *Directive {
+	for _, directive := range directives {
+		if policy.RequireReason && directive.Reason == "" {
+			continue
+		}
+		if directive.matches(issue) {
+			return directive
+		}
+	}
+	return nil
+}
+
+//allRan tells if every linter of names ran without failing, names being empty meaning every linter
+func allRan(names []string, ran map[string]bool) bool {
+	for _, name := range names {
+		if name != "all" && !ran[name] {
+			return false
+		}
+	}
+	return true
+}
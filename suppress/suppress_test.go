@@ -0,0 +1,219 @@
+package suppress_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/lagarciag/codenanny/config"
+	"github.com/lagarciag/codenanny/lint"
+	"github.com/lagarciag/codenanny/linters"
+	"github.com/lagarciag/codenanny/suppress"
+)
+
+func TestMain(t *testing.M) {
+	log.SetLevel(log.DebugLevel)
+	formatter := &log.TextFormatter{}
+	formatter.ForceColors = true
+	formatter.DisableTimestamp = true
+	log.SetFormatter(formatter)
+	v := t.Run()
+	os.Exit(v)
+
+}
+
+const source = `package nanny
+
+//nanny:file-ignore lll -- generated names
+
+import "os"
+
+//Remove removes a file
+//nanny:ignore errcheck -- best effort
+func Remove(path string) {
+	os.Remove(path)
+	os.Remove(path + ".bak")
+}
+
+func Close(f *os.File) {
+	f.Close() //nanny:ignore errcheck,unconvert
+	//nanny:ignore
+	f.Sync()
+	//nanny:skip gosec -- typo
+}
+`
+
+func TestParse(t *testing.T) {
+	directives, err := suppress.Parse("a.go", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []suppress.Directive{
+		{Path: "a.go", Line: 3, Col: 1, File: true, Linters: []string{"lll"}, Reason: "generated names", Start: 3, End: 3},
+		{Path: "a.go", Line: 8, Col: 1, Linters: []string{"errcheck"}, Reason: "best effort", Start: 8, End: 12},
+		{Path: "a.go", Line: 15, Col: 12, Linters: []string{"errcheck", "unconvert"}, Start: 15, End: 15},
+		{Path: "a.go", Line: 16, Col: 2, Start: 16, End: 17},
+		{Path: "a.go", Line: 18, Col: 2, Linters: []string{"gosec"}, Reason: "typo", Start: 18, End: 19,
+			Err: "unknown directive nanny:skip, expected nanny:ignore or nanny:file-ignore"},
+	}
+	if len(directives) != len(expected) {
+		t.Fatal("Unexpected directives:", directives)
+	}
+	for id, directive := range directives {
+		if !reflect.DeepEqual(*directive, expected[id]) {
+			t.Errorf("Expected %+v, got %+v", expected[id], *directive)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	issue := func(linter string, line int) lint.Issue {
+		return lint.Issue{Linter: linter, Path: "a.go", Line: line, Message: "boom", Severity: lint.SeverityError}
+	}
+	results := []lint.Result{
+		{Task: lint.Task{Linter: linters.Linter{Name: "errcheck"}}, Issues: []lint.Issue{issue("errcheck", 10), issue("errcheck", 11), issue("errcheck", 15)}},
+		{Task: lint.Task{Linter: linters.Linter{Name: "lll"}}, Issues: []lint.Issue{issue("lll", 1)}},
+		{Task: lint.Task{Linter: linters.Linter{Name: "vet"}}, Issues: []lint.Issue{issue("vet", 10), issue("vet", 17)}},
+		{Task: lint.Task{Linter: linters.Linter{Name: "unconvert"}}},
+	}
+
+	kept, suppressed := suppress.Apply(root, config.CodeNannyConfig{}, []string{"a.go"}, results, false)
+	if suppressed != 5 || len(kept) != 5 {
+		t.Fatal("Unexpected results:", suppressed, kept)
+	}
+	if issues := kept[2].Issues; len(issues) != 1 || issues[0].Line != 10 {
+		t.Error("Directives must only suppress the issues of their linters:", issues)
+	}
+	if problems := kept[4].Issues; len(problems) != 1 || problems[0].Linter != suppress.Name || problems[0].Line != 18 {
+		t.Error("Malformed directives must be reported:", problems)
+	}
+
+	policy := config.Directives{RequireReason: true, ReportUnused: true}
+	kept, suppressed = suppress.Apply(root, config.CodeNannyConfig{Directives: policy}, []string{"a.go"}, results, false)
+	if suppressed != 3 {
+		t.Error("Directives without reason must not suppress anything:", suppressed)
+	}
+	expected := []string{
+		"a.go:15:12: directive has no reason, add one after --",
+		"a.go:16:2: directive has no reason, add one after --",
+		"a.go:18:2: unknown directive nanny:skip, expected nanny:ignore or nanny:file-ignore",
+	}
+	problems := kept[len(kept)-1].Issues
+	lint.SortIssues(problems)
+	if len(problems) != len(expected) {
+		t.Fatal("Unexpected problems:", problems)
+	}
+	for id, problem := range problems {
+		if problem.String() != expected[id] {
+			t.Errorf("Expected %q, got %q", expected[id], problem.String())
+		}
+	}
+
+	//errcheck finds nothing now, its directives are not needed anymore
+	policy.RequireReason = false
+	results[0].Issues = nil
+	kept, _ = suppress.Apply(root, config.CodeNannyConfig{Directives: policy}, []string{"a.go"}, results, false)
+	problems = kept[len(kept)-1].Issues
+	lint.SortIssues(problems)
+	if len(problems) != 3 || problems[0].Line != 8 || problems[1].Line != 15 || problems[0].Severity != lint.SeverityWarning {
+		t.Error("Directives of linters that ran and found nothing must be reported:", problems)
+	}
+	kept, _ = suppress.Apply(root, config.CodeNannyConfig{Directives: policy}, []string{"a.go"}, results[1:], false)
+	if problems = kept[len(kept)-1].Issues; len(problems) != 1 {
+		t.Error("Directives of linters that did not run must not be reported:", problems)
+	}
+	kept, _ = suppress.Apply(root, config.CodeNannyConfig{Directives: policy}, []string{"a.go"}, results, true)
+	if problems = kept[len(kept)-1].Issues; len(problems) != 1 {
+		t.Error("Unused directives must not be reported when the run was interrupted:", problems)
+	}
+}
+
+func TestUnknownLinter(t *testing.T) {
+	if _, found := linters.Get(suppress.Name); found {
+		t.Fatal("The suppression issues must not share their name with a linter:", suppress.Name)
+	}
+	root := t.TempDir()
+	source := "package nanny\n\nimport \"os\"\n\nfunc Remove(path string) {\n\tos.Remove(path) //nanny:ignore errchek -- best effort\n}\n"
+	if err := os.WriteFile(filepath.Join(root, "a.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results := []lint.Result{{Task: lint.Task{Linter: linters.Linter{Name: "errcheck"}},
+		Issues: []lint.Issue{{Linter: "errcheck", Path: "a.go", Line: 6, Message: "boom", Severity: lint.SeverityError}}}}
+
+	kept, suppressed := suppress.Apply(root, config.CodeNannyConfig{}, []string{"a.go"}, results, false)
+	if suppressed != 0 || len(kept) != 2 {
+		t.Fatal("A directive naming an unknown linter must suppress nothing:", suppressed, kept)
+	}
+	if problems := kept[1].Issues; len(problems) != 1 || problems[0].String() != "a.go:6:18: unknown linter errchek, see codenanny linters" {
+		t.Error("A directive naming an unknown linter is malformed:", problems)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	root := t.TempDir()
+	source := "package nanny\n\n" +
+		"func A() {} //nanny:ignore golint until=2001-01-31 owner=alice -- legacy\n" +
+		"func B() {} //nanny:ignore golint until=2999-12-31 -- soon\n" +
+		"func C() {} //nanny:ignore golint until=someday\n"
+	if err := os.WriteFile(filepath.Join(root, "a.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	directives, err := suppress.Parse("a.go", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(directives) != 3 || directives[0].Owner != "alice" || directives[0].Until.Format(config.UntilLayout) != "2001-01-31" ||
+		!reflect.DeepEqual(directives[0].Linters, []string{"golint"}) || directives[2].Err == "" {
+		t.Fatal("Unexpected directives:", directives)
+	}
+
+	issue := func(linter string, line int, message string) lint.Issue {
+		return lint.Issue{Linter: linter, Path: "a.go", Line: line, Message: message, Severity: lint.SeverityError}
+	}
+	results := []lint.Result{
+		{Task: lint.Task{Linter: linters.Linter{Name: "golint"}}, Issues: []lint.Issue{issue("golint", 3, "boom"), issue("golint", 4, "boom")}},
+		{Task: lint.Task{Linter: linters.Linter{Name: "errcheck"}}, Issues: []lint.Issue{issue("errcheck", 5, "old"), issue("errcheck", 5, "new")}},
+	}
+	conf := config.CodeNannyConfig{IgnorePattern: map[string][]config.IgnorePattern{"errcheck": {
+		{Pattern: "old", Until: "2001-01-31", Owner: "bob"},
+		{Pattern: "new", Until: "2999-12-31"},
+	}}}
+
+	kept, suppressed := suppress.Apply(root, conf, []string{"a.go"}, results, false)
+	if suppressed != 4 {
+		t.Error("Expired suppressions must still apply:", suppressed)
+	}
+	expected := []string{
+		".codenanny: ignore pattern \"old\" of errcheck expired on 2001-01-31, owner bob, it hides 1 issue: a.go:5: old (errcheck)",
+		"a.go:3:13: directive expired on 2001-01-31, owner alice, it hides 1 issue: a.go:3: boom (golint)",
+		"a.go:5:13: invalid expiry date \"someday\", expected 2006-01-02",
+	}
+	problems := kept[len(kept)-1].Issues
+	lint.SortIssues(problems)
+	if len(problems) != len(expected) {
+		t.Fatal("Unexpected problems:", problems)
+	}
+	for id, problem := range problems {
+		if problem.String() != expected[id] {
+			t.Errorf("Expected %q, got %q", expected[id], problem.String())
+		}
+	}
+	if problems[0].Severity != lint.SeverityWarning {
+		t.Error("Expired suppressions must be warnings by default:", problems[0].Severity)
+	}
+
+	conf.ExpiredSuppressions = "error"
+	kept, _ = suppress.Apply(root, conf, []string{"a.go"}, results, false)
+	for _, problem := range kept[len(kept)-1].Issues {
+		if problem.Severity != lint.SeverityError {
+			t.Error("Expired suppressions must get the configured severity:", problem)
+		}
+	}
+}